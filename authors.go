@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+	"github.com/varrcan/generate-pretty-changelog/pkg/tmpl"
+)
+
+const (
+	defaultBotAuthor = `\[bot\]`
+	defaultBotText   = "{{ .Count }} dependency updates"
+)
+
+// matchAuthor returns true if any of the regexps matches the login, name or email of the author.
+func matchAuthor(res []*regexp.Regexp, e entry) bool {
+	for _, s := range []string{e.commit.Login, e.commit.AuthorName, e.commit.AuthorEmail} {
+		if s != "" && matchAny(res, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterAuthors keeps the entries whose author matches any of include, if
+// set, and removes the ones whose author matches any of exclude.
+func filterAuthors(include, exclude []string, entries []entry) ([]entry, error) {
+	includes, err := compileAll(include)
+	if err != nil {
+		return entries, err
+	}
+	excludes, err := compileAll(exclude)
+	if err != nil {
+		return entries, err
+	}
+	var result []entry
+	for _, e := range entries {
+		if len(includes) > 0 && !matchAuthor(includes, e) {
+			continue
+		}
+		if matchAuthor(excludes, e) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// collapseBots replaces the commits of bots with a single summary entry.
+func collapseBots(ctx *context.Context, entries []entry) ([]entry, error) {
+	cfg := ctx.Config.Changelog.Bots
+	if !cfg.Collapse {
+		return entries, nil
+	}
+	authors := cfg.Authors
+	if len(authors) == 0 {
+		authors = []string{defaultBotAuthor}
+	}
+	res, err := compileAll(authors)
+	if err != nil {
+		return entries, err
+	}
+
+	var result []entry
+	count, last := 0, 0
+	for _, e := range entries {
+		last = max(last, e.pos)
+		if matchAuthor(res, e) {
+			count++
+			continue
+		}
+		result = append(result, e)
+	}
+	if count == 0 {
+		return entries, nil
+	}
+
+	text := cfg.Text
+	if text == "" {
+		text = defaultBotText
+	}
+	line, err := tmpl.New(ctx).WithExtraFields(tmpl.Fields{
+		"Count": count,
+	}).Apply(text)
+	if err != nil {
+		return entries, fmt.Errorf("failed to apply template to bots.text: %w", err)
+	}
+	return append(result, entry{line: line, pos: last + 1}), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/config"
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+	"github.com/varrcan/generate-pretty-changelog/pkg/git"
+)
+
+func TestCollapseBots(t *testing.T) {
+	entries := []entry{
+		{commit: git.Commit{SHA: "a", Login: "jane"}, line: "a feat: login", pos: 0},
+		{commit: git.Commit{SHA: "b", Login: "dependabot[bot]"}, line: "b chore: bump x", pos: 1},
+		{commit: git.Commit{SHA: "c", AuthorName: "Renovate Bot", AuthorEmail: "bot@renovateapp.com"}, line: "c chore: bump y", pos: 2},
+		{commit: git.Commit{SHA: "d", AuthorName: "John"}, line: "d fix: crash", pos: 3},
+	}
+
+	for name, tt := range map[string]struct {
+		collapse bool
+		authors  []string
+		text     string
+		want     []string
+	}{
+		"disabled": {
+			want: []string{"a feat: login", "b chore: bump x", "c chore: bump y", "d fix: crash"},
+		},
+		"default authors": {
+			collapse: true,
+			want:     []string{"a feat: login", "c chore: bump y", "d fix: crash", "1 dependency updates"},
+		},
+		"authors by name and email": {
+			collapse: true,
+			authors:  []string{`\[bot\]`, `@renovateapp\.com$`},
+			want:     []string{"a feat: login", "d fix: crash", "2 dependency updates"},
+		},
+		"text": {
+			collapse: true,
+			authors:  []string{`\[bot\]`, `^Renovate`},
+			text:     "Bumped {{ .Count }} dependencies",
+			want:     []string{"a feat: login", "d fix: crash", "Bumped 2 dependencies"},
+		},
+		"no bots": {
+			collapse: true,
+			authors:  []string{`^nobody$`},
+			want:     []string{"a feat: login", "b chore: bump x", "c chore: bump y", "d fix: crash"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Changelog.Bots.Collapse = tt.collapse
+			cfg.Changelog.Bots.Authors = tt.authors
+			cfg.Changelog.Bots.Text = tt.text
+			result, err := collapseBots(context.New(cfg), entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range result {
+				got = append(got, e.line)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if last := result[len(result)-1]; last.commit.SHA == "" && last.pos != 4 {
+				t.Errorf("summary pos = %d, want 4", last.pos)
+			}
+		})
+	}
+
+	var cfg config.Config
+	cfg.Changelog.Bots.Collapse = true
+	cfg.Changelog.Bots.Authors = []string{"("}
+	if _, err := collapseBots(context.New(cfg), entries); err == nil {
+		t.Error("invalid author regexp: no error")
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/varrcan/generate-pretty-changelog/pkg/context"
 	"github.com/varrcan/generate-pretty-changelog/pkg/git"
+	"github.com/varrcan/generate-pretty-changelog/pkg/tmpl"
 )
 
 // errInvalidSortDirection happens when the sort order is invalid.
@@ -17,14 +20,17 @@ var errInvalidSortDirection = errors.New("invalid sort direction")
 
 const li = "* "
 
+const defaultOutput = "CHANGELOG.md"
+
 const (
-	useGit    = "git"
-	useGitHub = "github"
+	useGit           = "git"
+	useGitHub        = "github"
+	useGitHubGraphQL = "github-graphql"
 )
 
 // generate changelog
 func generate(ctx *context.Context) error {
-	if err := checkSortDirection(ctx.Config.Changelog.Sort); err != nil {
+	if err := checkSortDirection(ctx); err != nil {
 		return err
 	}
 
@@ -39,18 +45,77 @@ func generate(ctx *context.Context) error {
 	}
 	changelogElements := []string{changes}
 
+	footer, err := applyHeaderTemplate(ctx, ctx.Config.Changelog.Footer)
+	if err != nil {
+		return fmt.Errorf("failed to apply template to footer: %w", err)
+	}
+	if footer != "" {
+		changelogElements = append(changelogElements, footer)
+	}
+
 	ctx.ReleaseNotes = strings.Join(changelogElements, "\n\n")
 	if !strings.HasSuffix(ctx.ReleaseNotes, "\n") {
 		ctx.ReleaseNotes += "\n"
 	}
 
-	return os.WriteFile("CHANGELOG.md", []byte(ctx.ReleaseNotes), 0o644) //nolint: gosec
+	output, err := tmpl.New(ctx).Apply(ctx.Config.Changelog.Output)
+	if err != nil {
+		return fmt.Errorf("failed to apply template to output: %w", err)
+	}
+	if output == "" {
+		output = defaultOutput
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil { //nolint: gosec
+		return err
+	}
+
+	return os.WriteFile(output, []byte(ctx.ReleaseNotes), 0o644) //nolint: gosec
+}
+
+// applyHeaderTemplate applies the header and footer template, which also have
+// access to the URL comparing the previous and current release.
+func applyHeaderTemplate(ctx *context.Context, s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	var compareURL string
+	// resolving the repository needs a remote, so only do it when used.
+	if strings.Contains(s, "CompareURL") {
+		l, err := newRepoLinker(ctx)
+		if err != nil {
+			return "", fmt.Errorf("couldn't build compare URL: %w", err)
+		}
+		prev, current := comparePair(ctx)
+		compareURL = l.compareURL(prev, current)
+	}
+	return tmpl.New(ctx).WithExtraFields(tmpl.Fields{
+		"CompareURL": compareURL,
+	}).Apply(s)
 }
 
 type changelogGroup struct {
 	title   string
-	entries []string
+	entries []entry
 	order   int
+	scopes  string
+	sort    sortMode
+}
+
+// Group scope modes.
+const (
+	scopesHeading = "heading"
+	scopesBold    = "bold"
+)
+
+// scopeRe matches the scope of a conventional commit subject.
+var scopeRe = regexp.MustCompile(`^[[:word:]]+\(([^)]+)\)!?:`)
+
+// scope returns the conventional commit scope of the entry, if any.
+func (e entry) scope() string {
+	if m := scopeRe.FindStringSubmatch(e.commit.Subject); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return ""
 }
 
 func title(s string, level int) string {
@@ -80,66 +145,110 @@ func abbrevEntry(s string, abbr int) string {
 	}
 }
 
-func abbrev(entries []string, abbr int) []string {
-	result := make([]string, 0, len(entries))
-	for _, entry := range entries {
-		result = append(result, abbrevEntry(entry, abbr))
+func abbrev(entries []entry, abbr int) []entry {
+	result := make([]entry, 0, len(entries))
+	for _, e := range entries {
+		if e.commit.SHA != "" {
+			e.line = abbrevEntry(e.line, abbr)
+		}
+		result = append(result, e)
 	}
 	return result
 }
 
-func formatChangelog(ctx *context.Context, entries []string) (string, error) {
+func formatChangelog(ctx *context.Context, entries []entry) (string, error) {
 	entries = abbrev(entries, ctx.Config.Changelog.Abbrev)
 
-	result := []string{title("Changelog", 2)}
+	r, err := newEntryRenderer(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := applyHeaderTemplate(ctx, ctx.Config.Changelog.Header)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply template to header: %w", err)
+	}
+	if header == "" {
+		header = title("Changelog", 2)
+	}
+
+	result := []string{header}
 	if len(ctx.Config.Changelog.Groups) == 0 {
-		return strings.Join(append(result, filterAndPrefixItems(entries)...), newLineFor()), nil
+		items, err := r.items(filterEmpty(entries), false)
+		if err != nil {
+			return "", err
+		}
+		result = append(result, items...)
+		result = append(result, r.issues.section()...)
+		return strings.Join(result, newLineFor()), nil
 	}
 
+	// groups are matched by priority, the configured order breaks ties.
+	configGroups := slices.Clone(ctx.Config.Changelog.Groups)
+	sort.SliceStable(configGroups, func(i, j int) bool {
+		return configGroups[i].Priority > configGroups[j].Priority
+	})
+
+	// claimed entries are not available to the groups matched afterwards,
+	// matched ones are only kept out of the groups without regexp.
+	claimed := make([]bool, len(entries))
+	matched := make([]bool, len(entries))
+
 	var groups []changelogGroup
-	for _, group := range ctx.Config.Changelog.Groups {
+	for _, group := range configGroups {
+		groupTitle, err := tmpl.New(ctx).Apply(group.Title)
+		if err != nil {
+			return "", fmt.Errorf("failed to apply template to group %q: %w", group.Title, err)
+		}
+		switch group.Scopes {
+		case "", scopesHeading, scopesBold:
+		default:
+			return "", fmt.Errorf("invalid scopes %q of group %q", group.Scopes, group.Title)
+		}
+		mode, _ := parseSortMode(group.Sort)
 		item := changelogGroup{
-			title: title(group.Title, 3),
-			order: group.Order,
+			title:  title(groupTitle, 3),
+			order:  group.Order,
+			scopes: group.Scopes,
+			sort:   mode,
 		}
-		if group.Regexp == "" {
-			// If no regexp is provided, we purge all strikethrough entries and add remaining entries to the list
-			item.entries = filterAndPrefixItems(entries)
-			// clear array
-			entries = nil
-		} else {
-			re, err := regexp.Compile(group.Regexp)
+
+		// If no regexp is provided, we purge all strikethrough entries and add remaining entries to the list
+		var re *regexp.Regexp
+		if group.Regexp != "" {
+			re, err = regexp.Compile(group.Regexp)
 			if err != nil {
 				return "", fmt.Errorf("failed to group into %q: %w", group.Title, err)
 			}
+		}
+		exclusive := group.Exclusive == nil || *group.Exclusive
 
-			i := 0
-			for _, entry := range entries {
-				match := re.MatchString(entry)
-				if match {
-					item.entries = append(item.entries, li+entry)
-				} else {
-					// Keep unmatched entry.
-					entries[i] = entry
-					i++
-				}
+		for i, e := range entries {
+			if claimed[i] || e.line == "" {
+				continue
 			}
-			entries = entries[:i]
+			if (re == nil && matched[i]) || (re != nil && !re.MatchString(e.line)) {
+				continue
+			}
+			item.entries = append(item.entries, e)
+			matched[i] = true
+			claimed[i] = exclusive
 		}
 		groups = append(groups, item)
-
-		if len(entries) == 0 {
-			break // No more entries to process.
-		}
 	}
 
 	sort.Slice(groups, groupSort(groups))
 	for _, group := range groups {
 		if len(group.entries) > 0 {
+			items, err := r.group(group)
+			if err != nil {
+				return "", err
+			}
 			result = append(result, group.title)
-			result = append(result, group.entries...)
+			result = append(result, items...)
 		}
 	}
+	result = append(result, r.issues.section()...)
 	return strings.Join(result, newLineFor()), nil
 }
 
@@ -149,50 +258,194 @@ func groupSort(groups []changelogGroup) func(i, j int) bool {
 	}
 }
 
-func filterAndPrefixItems(ss []string) []string {
-	var r []string
-	for _, s := range ss {
-		if s != "" {
-			r = append(r, li+s)
+// entryRenderer renders entries as list items, linking their references.
+type entryRenderer struct {
+	rewriter    rewriter
+	links       *linker
+	issues      *issueLinker
+	releaseNote string
+	abbrev      int
+}
+
+func newEntryRenderer(ctx *context.Context) (entryRenderer, error) {
+	links, err := newLinker(ctx)
+	if err != nil {
+		return entryRenderer{}, err
+	}
+	issues, err := newIssueLinker(ctx)
+	if err != nil {
+		return entryRenderer{}, err
+	}
+	rw, err := newRewriter(ctx)
+	if err != nil {
+		return entryRenderer{}, err
+	}
+	return entryRenderer{
+		rewriter:    rw,
+		links:       links,
+		issues:      issues,
+		releaseNote: strings.ToLower(ctx.Config.Changelog.ReleaseNote),
+		abbrev:      ctx.Config.Changelog.Abbrev,
+	}, nil
+}
+
+// item returns the entry as a list item, prefixed with its bold scope if asked to.
+func (r entryRenderer) item(e entry, boldScope bool) (string, error) {
+	if notes := e.commit.Trailers()[r.releaseNote]; r.releaseNote != "" && len(notes) > 0 {
+		e.line = strings.Replace(e.line, e.commit.Subject, strings.Join(notes, " "), 1)
+	}
+	sha, msg := r.splitSHA(e)
+	msg = r.rewriter.rewrite(msg)
+	if scope := e.scope(); boldScope && scope != "" {
+		msg = fmt.Sprintf("**%s:** %s", scope, msg)
+	}
+	e.line = msg
+	msg, err := r.issues.render(e)
+	if err != nil {
+		return "", err
+	}
+	return li + r.links.render(sha, msg), nil
+}
+
+// splitSHA splits the line of the entry into the commit SHA it starts with,
+// including the separator, and the message. Entries without a commit, like
+// the bot summaries, and lines whose SHA was removed with abbrev -1 have none.
+func (r entryRenderer) splitSHA(e entry) (sha, msg string) {
+	if e.commit.SHA == "" || r.abbrev == -1 {
+		return "", e.line
+	}
+	commit, rest, found := strings.Cut(e.line, " ")
+	if !found {
+		return "", e.line
+	}
+	return commit + " ", rest
+}
+
+// items returns the entries as list items.
+func (r entryRenderer) items(entries []entry, boldScope bool) ([]string, error) {
+	var result []string
+	for _, e := range entries {
+		line, err := r.item(e, boldScope)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, line)
+	}
+	return result, nil
+}
+
+// group returns the entries of the group as list items, sub-grouped by scope
+// under their own heading if asked to. Entries without scope come first.
+func (r entryRenderer) group(group changelogGroup) ([]string, error) {
+	group.entries = group.sort.sort(group.entries)
+	if group.scopes != scopesHeading {
+		return r.items(group.entries, group.scopes == scopesBold)
+	}
+
+	var unscoped []entry
+	var scopes []string
+	byScope := map[string][]entry{}
+	for _, e := range group.entries {
+		scope := e.scope()
+		if scope == "" {
+			unscoped = append(unscoped, e)
+			continue
+		}
+		if _, ok := byScope[scope]; !ok {
+			scopes = append(scopes, scope)
+		}
+		byScope[scope] = append(byScope[scope], e)
+	}
+	sort.Strings(scopes)
+
+	result, err := r.items(unscoped, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		items, err := r.items(byScope[scope], false)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, title(scope, 4))
+		result = append(result, items...)
+	}
+	return result, nil
+}
+
+func filterEmpty(entries []entry) []entry {
+	var r []entry
+	for _, e := range entries {
+		if e.line != "" {
+			r = append(r, e)
 		}
 	}
 	return r
 }
 
-func checkSortDirection(mode string) error {
-	switch mode {
-	case "", "asc", "desc":
-		return nil
-	default:
-		return errInvalidSortDirection
+func checkSortDirection(ctx *context.Context) error {
+	if _, err := parseSortMode(ctx.Config.Changelog.Sort); err != nil {
+		return err
 	}
+	for _, group := range ctx.Config.Changelog.Groups {
+		if _, err := parseSortMode(group.Sort); err != nil {
+			return fmt.Errorf("group %q: %w", group.Title, err)
+		}
+	}
+	return nil
 }
 
-func buildChangelog(ctx *context.Context) ([]string, error) {
+func buildChangelog(ctx *context.Context) ([]entry, error) {
 	l, err := getChangeLogger(ctx)
 	if err != nil {
 		return nil, err
 	}
-	log, err := l.Log(ctx)
+	entries, err := l.Log(ctx)
 	if err != nil {
 		return nil, err
 	}
-	entries := strings.Split(log, "\n")
-	if lastLine := entries[len(entries)-1]; strings.TrimSpace(lastLine) == "" {
-		entries = entries[0 : len(entries)-1]
+	if ctx.Config.Changelog.Dedupe {
+		entries = dedupeEntries(ctx, entries)
+	}
+	if ctx.Config.Changelog.DropReverted {
+		entries = dropReverted(entries)
 	}
 	entries, err = filterEntries(ctx, entries)
 	if err != nil {
 		return entries, err
 	}
+	entries, err = collapseBots(ctx, entries)
+	if err != nil {
+		return entries, err
+	}
 	return sortEntries(ctx, entries), nil
 }
 
-func filterEntries(ctx *context.Context, entries []string) ([]string, error) {
+func filterEntries(ctx *context.Context, entries []entry) ([]entry, error) {
 	filters := ctx.Config.Changelog.Filters
-	if len(filters.Include) > 0 {
-		var newEntries []string
-		for _, filter := range filters.Include {
+	entries, err := filterSubjects(filters.Include, filters.Exclude, entries)
+	if err != nil {
+		return entries, err
+	}
+	entries, err = filterBodies(filters.Body.Include, filters.Body.Exclude, entries)
+	if err != nil {
+		return entries, err
+	}
+	entries, err = filterTrailers(filters.Trailers.Include, true, entries)
+	if err != nil {
+		return entries, err
+	}
+	entries, err = filterTrailers(filters.Trailers.Exclude, false, entries)
+	if err != nil {
+		return entries, err
+	}
+	return filterAuthors(filters.Authors.Include, filters.Authors.Exclude, entries)
+}
+
+func filterSubjects(include, exclude []string, entries []entry) ([]entry, error) {
+	if len(include) > 0 {
+		var newEntries []entry
+		for _, filter := range include {
 			r, err := regexp.Compile(filter)
 			if err != nil {
 				return entries, err
@@ -201,7 +454,7 @@ func filterEntries(ctx *context.Context, entries []string) ([]string, error) {
 		}
 		return newEntries, nil
 	}
-	for _, filter := range filters.Exclude {
+	for _, filter := range exclude {
 		r, err := regexp.Compile(filter)
 		if err != nil {
 			return entries, err
@@ -211,40 +464,110 @@ func filterEntries(ctx *context.Context, entries []string) ([]string, error) {
 	return entries, nil
 }
 
-func sortEntries(ctx *context.Context, entries []string) []string {
-	direction := ctx.Config.Changelog.Sort
-	if direction == "" {
-		return entries
-	}
-	result := make([]string, len(entries))
-	copy(result, entries)
-	sort.Slice(result, func(i, j int) bool {
-		imsg := extractCommitInfo(result[i])
-		jmsg := extractCommitInfo(result[j])
-		if direction == "asc" {
-			return strings.Compare(imsg, jmsg) < 0
+func sortEntries(ctx *context.Context, entries []entry) []entry {
+	mode, _ := parseSortMode(ctx.Config.Changelog.Sort)
+	return mode.sort(entries)
+}
+
+func keep(filter *regexp.Regexp, entries []entry) (result []entry) {
+	for _, e := range entries {
+		if filter.MatchString(extractCommitInfo(e.line)) {
+			result = append(result, e)
 		}
-		return strings.Compare(imsg, jmsg) > 0
-	})
+	}
 	return result
 }
 
-func keep(filter *regexp.Regexp, entries []string) (result []string) {
-	for _, entry := range entries {
-		if filter.MatchString(extractCommitInfo(entry)) {
-			result = append(result, entry)
+func remove(filter *regexp.Regexp, entries []entry) (result []entry) {
+	for _, e := range entries {
+		if !filter.MatchString(extractCommitInfo(e.line)) {
+			result = append(result, e)
 		}
 	}
 	return result
 }
 
-func remove(filter *regexp.Regexp, entries []string) (result []string) {
-	for _, entry := range entries {
-		if !filter.MatchString(extractCommitInfo(entry)) {
-			result = append(result, entry)
+// filterBodies keeps the entries whose body matches any of include, if set,
+// and removes the ones whose body matches any of exclude.
+func filterBodies(include, exclude []string, entries []entry) ([]entry, error) {
+	includes, err := compileAll(include)
+	if err != nil {
+		return entries, err
+	}
+	excludes, err := compileAll(exclude)
+	if err != nil {
+		return entries, err
+	}
+	var result []entry
+	for _, e := range entries {
+		if len(includes) > 0 && !matchAny(includes, e.commit.Body) {
+			continue
+		}
+		if matchAny(excludes, e.commit.Body) {
+			continue
 		}
+		result = append(result, e)
 	}
-	return result
+	return result, nil
+}
+
+// filterTrailers keeps (or removes) the entries having any of the trailers
+// with a value matching the filter regexp.
+func filterTrailers(filters map[string]string, include bool, entries []entry) ([]entry, error) {
+	if len(filters) == 0 {
+		return entries, nil
+	}
+	res := map[string]*regexp.Regexp{}
+	for key, filter := range filters {
+		r, err := regexp.Compile(filter)
+		if err != nil {
+			return entries, fmt.Errorf("invalid filter of trailer %q: %w", key, err)
+		}
+		res[strings.ToLower(key)] = r
+	}
+	var result []entry
+	for _, e := range entries {
+		if hasTrailer(res, e.commit) == include {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func hasTrailer(res map[string]*regexp.Regexp, commit git.Commit) bool {
+	for key, values := range commit.Trailers() {
+		r, ok := res[key]
+		if !ok {
+			continue
+		}
+		for _, value := range values {
+			if r.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compileAll(filters []string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	for _, filter := range filters {
+		r, err := regexp.Compile(filter)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, r := range res {
+		if r.MatchString(s) {
+			return true
+		}
+	}
+	return false
 }
 
 func extractCommitInfo(line string) string {
@@ -258,18 +581,28 @@ func getChangeLogger(ctx *context.Context) (changeLogger, error) {
 	case "":
 		return gitChangeLogger{}, nil
 	case useGitHub:
-		return newSCMChangeLogger(ctx)
+		return newSCMChangeLogger(ctx, git.NewClient)
+	case useGitHubGraphQL:
+		return newSCMChangeLogger(ctx, git.NewGraphQLClient)
 	default:
 		return nil, fmt.Errorf("invalid changelog.use: %q", ctx.Config.Changelog.Use)
 	}
 }
 
 type changeLogger interface {
-	Log(ctx *context.Context) (string, error)
+	Log(ctx *context.Context) ([]entry, error)
+}
+
+// entry is a changelog line along with the commit it was built from.
+type entry struct {
+	commit git.Commit
+	line   string
+	// pos is the position of the commit in history, oldest first.
+	pos int
 }
 
-func newSCMChangeLogger(ctx *context.Context) (changeLogger, error) {
-	cli, err := git.NewClient(ctx)
+func newSCMChangeLogger(ctx *context.Context, newClient func(*context.Context) (git.Client, error)) (changeLogger, error) {
+	cli, err := newClient(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -299,21 +632,45 @@ type scmChangeLogger struct {
 }
 
 // Log returns a changelog
-func (c *scmChangeLogger) Log(ctx *context.Context) (string, error) {
+func (c *scmChangeLogger) Log(ctx *context.Context) ([]entry, error) {
 	prev, current := comparePair(ctx)
-	return c.client.Changelog(ctx, c.repo, prev, current)
+	commits, err := c.client.Changelog(ctx, c.repo, prev, current)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]entry, 0, len(commits))
+	for i, commit := range commits {
+		entries = append(entries, entry{
+			commit: commit,
+			line:   fmt.Sprintf("%s: %s (@%s)", commit.SHA, commit.Subject, commit.Login),
+			pos:    i,
+		})
+	}
+	return entries, nil
 }
 
 // Log returns a changelog
-func (g gitChangeLogger) Log(ctx *context.Context) (string, error) {
-	args := []string{"log", "--pretty=oneline", "--abbrev-commit", "--no-decorate", "--no-color"}
+func (g gitChangeLogger) Log(ctx *context.Context) ([]entry, error) {
+	var revs []string
 	prev, current := comparePair(ctx)
 	if validSHA1.MatchString(prev) {
-		args = append(args, prev, current)
+		revs = append(revs, prev, current)
 	} else {
-		args = append(args, fmt.Sprintf("tags/%s..tags/%s", ctx.Git.PreviousTag, ctx.Git.CurrentTag))
+		revs = append(revs, fmt.Sprintf("tags/%s..tags/%s", ctx.Git.PreviousTag, ctx.Git.CurrentTag))
 	}
-	return git.Exec(ctx, args...)
+	commits, err := git.Log(ctx, revs...)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]entry, 0, len(commits))
+	for i, commit := range commits {
+		entries = append(entries, entry{
+			commit: commit,
+			line:   commit.SHA + " " + commit.Subject,
+			pos:    len(commits) - i, // git log lists the newest commits first.
+		})
+	}
+	return entries, nil
 }
 
 func comparePair(ctx *context.Context) (prev string, current string) {
@@ -0,0 +1,510 @@
+package main
+
+import (
+	stdctx "context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/config"
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+	"github.com/varrcan/generate-pretty-changelog/pkg/git"
+)
+
+// testRepo is a throwaway git repository.
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git("init", "--quiet", "--initial-branch=main")
+	r.git("remote", "add", "origin", "git@github.com:acme/app.git")
+	return r
+}
+
+// git runs a git command in the repository with a fixed identity.
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{
+		"-c", "user.name=Jane Doe",
+		"-c", "user.email=jane@example.com",
+		"-c", "commit.gpgsign=false",
+		"-c", "tag.gpgsign=false",
+	}, args...)...)
+	cmd.Dir = r.dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *testRepo) commit(msg string) {
+	r.t.Helper()
+	r.git("commit", "--quiet", "--allow-empty", "-m", msg)
+}
+
+// generate runs the whole pipeline in the repository and returns the release notes.
+func (r *testRepo) generate(cfg config.Config) (string, error) {
+	r.t.Helper()
+	cfg.Changelog.Output = filepath.Join(r.t.TempDir(), "CHANGELOG.md")
+	ctx := context.New(cfg)
+	ctx.Token = "token"
+	ctx.Dir = r.dir
+	if err := git.Run(ctx); err != nil {
+		return "", err
+	}
+	if err := generate(ctx); err != nil {
+		return "", err
+	}
+	return ctx.ReleaseNotes, nil
+}
+
+func TestScenarios(t *testing.T) {
+	for name, tt := range map[string]struct {
+		setup func(r *testRepo)
+		want  string
+	}{
+		"first release": {
+			setup: func(r *testRepo) {
+				r.commit("feat: initial")
+				r.commit("fix: crash")
+				r.git("tag", "v1.0.0")
+			},
+			want: "## Changelog\n* feat: initial\n* fix: crash\n",
+		},
+		"between tags": {
+			setup: func(r *testRepo) {
+				r.commit("feat: initial")
+				r.git("tag", "v1.0.0")
+				r.commit("feat: login")
+				r.commit("fix: logout")
+				r.git("tag", "-a", "v1.1.0", "-m", "v1.1.0")
+			},
+			want: "## Changelog\n* feat: login\n* fix: logout\n",
+		},
+		"pre-releases": {
+			setup: func(r *testRepo) {
+				r.commit("feat: initial")
+				r.git("tag", "v1.0.0")
+				r.commit("feat: login")
+				r.git("tag", "v1.1.0-rc.1")
+				r.commit("fix: login redirect")
+				r.git("tag", "v1.1.0")
+			},
+			want: "## Changelog\n* fix: login redirect\n",
+		},
+		"merges": {
+			setup: func(r *testRepo) {
+				r.commit("feat: initial")
+				r.git("tag", "v1.0.0")
+				r.git("checkout", "--quiet", "-b", "feature")
+				r.commit("feat: search")
+				r.git("checkout", "--quiet", "main")
+				r.commit("fix: typo")
+				r.git("merge", "--quiet", "--no-ff", "-m", "Merge branch 'feature'", "feature")
+				r.git("tag", "v1.1.0")
+			},
+			want: "## Changelog\n* Merge branch 'feature'\n* feat: search\n* fix: typo\n",
+		},
+	} {
+		for _, backend := range []string{"cli", "go-git"} {
+			t.Run(name+"/"+backend, func(t *testing.T) {
+				r := newTestRepo(t)
+				tt.setup(r)
+				var cfg config.Config
+				cfg.Git.Backend = backend
+				cfg.Changelog.Use = "git"
+				cfg.Changelog.Sort = "asc"
+				cfg.Changelog.Abbrev = -1
+				got, err := r.generate(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// recordingRunner runs git through the CLI and records the commands.
+type recordingRunner struct {
+	git.CLI
+	commands []string
+}
+
+func (r *recordingRunner) Run(ctx stdctx.Context, env []string, stdin io.Reader, args ...string) (string, error) {
+	r.commands = append(r.commands, args[0])
+	return r.CLI.Run(ctx, env, stdin, args...)
+}
+
+func TestScenarioGitRunner(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("feat: initial")
+	r.git("tag", "v1.0.0")
+	r.commit("feat: login")
+	r.git("tag", "v1.1.0")
+
+	var cfg config.Config
+	cfg.Changelog.Use = "git"
+	cfg.Changelog.Abbrev = -1
+	cfg.Changelog.Output = filepath.Join(t.TempDir(), "CHANGELOG.md")
+	ctx := context.New(cfg)
+	runner := &recordingRunner{CLI: git.CLI{Dir: r.dir}}
+	ctx.GitRunner = runner
+	if err := git.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := generate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "## Changelog\n* feat: login\n"; ctx.ReleaseNotes != want {
+		t.Errorf("got:\n%s\nwant:\n%s", ctx.ReleaseNotes, want)
+	}
+	if !slices.Contains(runner.commands, "log") {
+		t.Errorf("git log didn't go through the runner: %v", runner.commands)
+	}
+}
+
+func TestScenarioNoTags(t *testing.T) {
+	for _, backend := range []string{"cli", "go-git"} {
+		t.Run(backend, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: initial")
+
+			var cfg config.Config
+			cfg.Git.Backend = backend
+			cfg.Changelog.Use = "git"
+			_, err := r.generate(cfg)
+			if err == nil || err.Error() != "git doesn't contain any tags" {
+				t.Fatalf("err = %v", err)
+			}
+		})
+	}
+}
+
+func TestScenarioGitHub(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("feat: initial")
+	r.git("tag", "v1.0.0")
+	r.commit("feat: login")
+	r.git("tag", "v1.1.0")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/acme/app/compare/v1.0.0...v1.1.0", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"total_commits": 2,
+			"commits": []map[string]any{
+				{
+					"sha":    "1111111111111111111111111111111111111111",
+					"commit": map[string]any{"message": "feat: login (#2)"},
+					"author": map[string]any{"login": "jane"},
+				},
+				{
+					"sha":    "2222222222222222222222222222222222222222",
+					"commit": map[string]any{"message": "fix: logout\n\nCloses #3"},
+					"author": map[string]any{"login": "john"},
+				},
+			},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var cfg config.Config
+	cfg.GitHubURLs.API = srv.URL + "/api/v3/"
+	cfg.Changelog.Use = "github"
+	cfg.Changelog.Abbrev = -1
+	got, err := r.generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "## Changelog\n* feat: login (#2) (@jane)\n* fix: logout (@john)\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestScenarioShallowClone(t *testing.T) {
+	origin := newTestRepo(t)
+	origin.commit("feat: initial")
+	origin.git("tag", "v1.0.0")
+	origin.commit("feat: login")
+	origin.commit("fix: logout")
+	origin.git("tag", "v1.1.0")
+
+	for name, tt := range map[string]struct {
+		backend, mode, want string
+	}{
+		"fetch": {"cli", "fetch", "## Changelog\n* feat: login\n* fix: logout\n"},
+		"fail":  {"cli", "fail", ""},
+		// go-git can't fetch the history, so only the cloned commit is listed.
+		"go-git fetch": {"go-git", "fetch", "## Changelog\n* fix: logout\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := &testRepo{t: t, dir: t.TempDir()}
+			r.git("clone", "--quiet", "--depth=1", "--no-tags", "file://"+origin.dir, ".")
+			r.git("tag", "v1.1.0")
+
+			var cfg config.Config
+			cfg.Git.Backend = tt.backend
+			cfg.Git.Shallow = tt.mode
+			cfg.Changelog.Use = "git"
+			cfg.Changelog.Sort = "asc"
+			cfg.Changelog.Abbrev = -1
+			got, err := r.generate(cfg)
+			if tt.want == "" {
+				if err == nil || !strings.Contains(err.Error(), "shallow clone detected") {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScenarioBackendsSHAs(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("feat: initial")
+	r.git("tag", "v1.0.0")
+	r.commit("feat: login")
+	r.commit("fix: logout")
+	r.git("tag", "v1.1.0")
+
+	want := "## Changelog\n"
+	for _, line := range strings.Split(r.git("log", "--format=%h %s", "--reverse", "v1.0.0..v1.1.0"), "\n") {
+		want += "* " + line + "\n"
+	}
+	for _, backend := range []string{"cli", "go-git"} {
+		t.Run(backend, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Git.Backend = backend
+			cfg.Changelog.Use = "git"
+			cfg.Changelog.Sort = "asc"
+			got, err := r.generate(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestScenarioHeader(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("feat: initial")
+	r.git("tag", "v1.0.0")
+	r.commit("feat: login")
+	r.git("tag", "v1.1.0")
+	created, err := time.Parse(time.RFC3339, r.git("for-each-ref", "--format=%(creatordate:iso-strict)", "refs/tags/v1.1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config.Config
+	cfg.Changelog.Use = "git"
+	cfg.Changelog.Abbrev = -1
+	cfg.Changelog.Header = "## {{ .Version }} ({{ .Date }})\n\n{{ .CompareURL }}"
+	got, err := r.generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "## 1.1.0 (" + created.UTC().Format("2006-01-02") + ")\n\n" +
+		"https://github.com/acme/app/compare/v1.0.0...v1.1.0\n* feat: login\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// yamlConfig loads a config from its YAML source.
+func yamlConfig(t *testing.T, src string) config.Config {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "changelog.yaml")
+	if err := os.WriteFile(file, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestFormatChangelogGroups(t *testing.T) {
+	var entries []entry
+	for _, subject := range []string{"feat: login", "feat!: drop v1", "feat(web): dark mode", "fix: crash", "chore: deps"} {
+		entries = append(entries, entry{commit: git.Commit{Subject: subject}, line: subject})
+	}
+
+	for name, tt := range map[string]struct {
+		groups string
+		want   string
+	}{
+		"first match claims": {
+			groups: `
+    - title: Features
+      regexp: '^feat'
+      order: 0
+    - title: Fixes
+      regexp: '^fix'
+      order: 1
+    - title: Others
+      order: 2`,
+			want: "## Changelog\n" +
+				"### Features\n* feat: login\n* feat!: drop v1\n* feat(web): dark mode\n" +
+				"### Fixes\n* fix: crash\n" +
+				"### Others\n* chore: deps",
+		},
+		"priority": {
+			groups: `
+    - title: Features
+      regexp: '^feat'
+      order: 0
+    - title: Breaking changes
+      regexp: '!:'
+      order: 1
+      priority: 1
+    - title: Others
+      order: 2`,
+			want: "## Changelog\n" +
+				"### Features\n* feat: login\n* feat(web): dark mode\n" +
+				"### Breaking changes\n* feat!: drop v1\n" +
+				"### Others\n* fix: crash\n* chore: deps",
+		},
+		"not exclusive": {
+			groups: `
+    - title: Features
+      regexp: '^feat'
+      exclusive: false
+      order: 0
+    - title: Web
+      regexp: '\(web\)'
+      order: 1
+    - title: Others
+      order: 2`,
+			want: "## Changelog\n" +
+				"### Features\n* feat: login\n* feat!: drop v1\n* feat(web): dark mode\n" +
+				"### Web\n* feat(web): dark mode\n" +
+				"### Others\n* fix: crash\n* chore: deps",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := yamlConfig(t, "changelog:\n  groups:"+tt.groups+"\n")
+			got, err := formatChangelog(context.New(cfg), entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// change writes content to file and commits it.
+func (r *testRepo) change(file, content, msg string) {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.dir, file), []byte(content), 0o600); err != nil {
+		r.t.Fatal(err)
+	}
+	r.git("add", file)
+	r.git("commit", "--quiet", "-m", msg)
+}
+
+func TestScenarioDedupe(t *testing.T) {
+	for name, tt := range map[string]struct {
+		setup func(r *testRepo)
+		want  string
+	}{
+		"same subject": {
+			setup: func(r *testRepo) {
+				r.change("a.txt", "a", "fix: crash")
+				r.change("b.txt", "b", "fix: crash")
+			},
+			want: "## Changelog\n* fix: crash\n",
+		},
+		"same patch": {
+			setup: func(r *testRepo) {
+				r.git("checkout", "--quiet", "-b", "backport")
+				r.change("a.txt", "a", "fix: crash")
+				r.git("checkout", "--quiet", "main")
+				r.change("a.txt", "a", "fix: crash on start")
+				r.git("merge", "--quiet", "--no-ff", "-m", "Merge branch 'backport'", "backport")
+			},
+			want: "## Changelog\n* Merge branch 'backport'\n* fix: crash\n",
+		},
+		"revert": {
+			setup: func(r *testRepo) {
+				r.change("a.txt", "a", "feat: search")
+				r.git("revert", "--no-edit", "HEAD")
+				r.change("b.txt", "b", "fix: crash")
+			},
+			want: "## Changelog\n* fix: crash\n",
+		},
+		"reverted revert": {
+			setup: func(r *testRepo) {
+				r.change("a.txt", "a", "feat: search")
+				r.git("revert", "--no-edit", "HEAD")
+				r.git("revert", "--no-edit", "HEAD")
+			},
+			want: "## Changelog\n* feat: search\n",
+		},
+		"relanded": {
+			setup: func(r *testRepo) {
+				r.change("a.txt", "a", "feat: search")
+				r.git("revert", "--no-edit", "HEAD")
+				r.change("a.txt", "a", "feat: search")
+			},
+			want: "## Changelog\n* feat: search\n",
+		},
+	} {
+		for _, backend := range []string{"cli", "go-git"} {
+			t.Run(name+"/"+backend, func(t *testing.T) {
+				r := newTestRepo(t)
+				r.commit("feat: initial")
+				r.git("tag", "v1.0.0")
+				tt.setup(r)
+				r.git("tag", "v1.1.0")
+
+				var cfg config.Config
+				cfg.Git.Backend = backend
+				cfg.Changelog.Use = "git"
+				cfg.Changelog.Sort = "asc"
+				cfg.Changelog.Abbrev = -1
+				cfg.Changelog.Dedupe = true
+				cfg.Changelog.DropReverted = true
+				got, err := r.generate(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+	"github.com/varrcan/generate-pretty-changelog/pkg/git"
+)
+
+var (
+	// revertRe matches the subject of commits created by git revert.
+	revertRe = regexp.MustCompile(`^Revert "(.+)"$`)
+	// revertedSHARe matches the body of commits created by git revert.
+	revertedSHARe = regexp.MustCompile(`This reverts commit ([0-9a-f]{7,40})`)
+)
+
+// byPos returns the indexes of the entries, oldest first or newest first.
+func byPos(entries []entry, newestFirst bool) []int {
+	idx := make([]int, len(entries))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		if newestFirst {
+			return entries[idx[i]].pos > entries[idx[j]].pos
+		}
+		return entries[idx[i]].pos < entries[idx[j]].pos
+	})
+	return idx
+}
+
+// without returns the entries not dropped, in their original order.
+func without(entries []entry, dropped []bool) []entry {
+	var result []entry
+	for i, e := range entries {
+		if !dropped[i] {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// isRevert returns true if the entry is a commit created by git revert.
+func isRevert(e entry) bool {
+	return revertRe.MatchString(e.commit.Subject) || revertedSHARe.MatchString(e.commit.Body)
+}
+
+// sameSHA returns true if both, possibly abbreviated, SHAs name the same commit.
+func sameSHA(a, b string) bool {
+	return a != "" && b != "" && (strings.HasPrefix(a, b) || strings.HasPrefix(b, a))
+}
+
+// dedupeEntries drops the entries having the same subject or patch ID as an
+// older one, e.g. cherry-picks of a commit already in the release. Commits
+// reverted before are not duplicated by landing them again.
+func dedupeEntries(ctx *context.Context, entries []entry) []entry {
+	patchIDs := patchIDsOf(ctx, entries)
+	subjects := map[string]bool{}
+	patches := map[string]bool{}
+	dropped := make([]bool, len(entries))
+	var older []int // newest first, like revertedBy expects.
+	for _, i := range byPos(entries, false) {
+		older = append([]int{i}, older...)
+		if isRevert(entries[i]) {
+			// reverts of reverts have the patch ID of the original change,
+			// and a reverted change landing again is no duplicate.
+			if j := revertedBy(entries, dropped, older[1:], entries[i]); j >= 0 {
+				delete(subjects, entries[j].commit.Subject)
+				delete(patches, patchIDs[j])
+			}
+			continue
+		}
+		subject := entries[i].commit.Subject
+		patch := patchIDs[i]
+		if subjects[subject] || (patch != "" && patches[patch]) {
+			dropped[i] = true
+			continue
+		}
+		subjects[subject] = true
+		if patch != "" {
+			patches[patch] = true
+		}
+	}
+	return without(entries, dropped)
+}
+
+// patchIDsOf returns the patch ID of the entries by index. Patch IDs are
+// computed from the local repository, so commits missing from it are skipped.
+func patchIDsOf(ctx *context.Context, entries []entry) map[int]string {
+	shas := make([]string, 0, len(entries))
+	for _, e := range entries {
+		shas = append(shas, e.commit.SHA)
+	}
+	ids, err := git.PatchIDs(ctx, shas...)
+	if err != nil {
+		fmt.Println("could not compute patch IDs, deduplicating by subject only:", err)
+		return nil
+	}
+
+	result := map[int]string{}
+	for i, e := range entries {
+		if id, ok := ids[e.commit.SHA]; ok {
+			result[i] = id
+			continue
+		}
+		for sha, id := range ids {
+			if sameSHA(sha, e.commit.SHA) {
+				result[i] = id
+				break
+			}
+		}
+	}
+	return result
+}
+
+// dropReverted drops the commits reverted within the release along with their
+// reverts. Reverts are paired newest first, so reverted reverts cancel out.
+func dropReverted(entries []entry) []entry {
+	dropped := make([]bool, len(entries))
+	newestFirst := byPos(entries, true)
+	for n, i := range newestFirst {
+		if dropped[i] {
+			continue
+		}
+		if j := revertedBy(entries, dropped, newestFirst[n+1:], entries[i]); j >= 0 {
+			dropped[i] = true
+			dropped[j] = true
+		}
+	}
+	return without(entries, dropped)
+}
+
+// revertedBy returns the index of the commit reverted by e among the older
+// candidates, or -1 if e is not a revert of one of them.
+func revertedBy(entries []entry, dropped []bool, older []int, e entry) int {
+	sha := ""
+	if m := revertedSHARe.FindStringSubmatch(e.commit.Body); m != nil {
+		sha = m[1]
+	}
+	subject := ""
+	if m := revertRe.FindStringSubmatch(e.commit.Subject); m != nil {
+		subject = m[1]
+	}
+	if sha == "" && subject == "" {
+		return -1
+	}
+	for _, j := range older {
+		if dropped[j] {
+			continue
+		}
+		if sha != "" && sameSHA(entries[j].commit.SHA, sha) {
+			return j
+		}
+		if sha == "" && entries[j].commit.Subject == subject {
+			return j
+		}
+	}
+	return -1
+}
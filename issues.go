@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+	"github.com/varrcan/generate-pretty-changelog/pkg/tmpl"
+)
+
+// issueIDGroup is the name of the capture group holding the issue ID.
+const issueIDGroup = "id"
+
+// issueTracker links references matching re to url.
+type issueTracker struct {
+	re  *regexp.Regexp
+	url string
+}
+
+// issueRef is an issue referenced by a commit.
+type issueRef struct {
+	id  string
+	url string
+}
+
+// issueMatch is an issueRef found at start:end of a line.
+type issueMatch struct {
+	issueRef
+	start, end int
+}
+
+// issueLinker extracts issue references from commits and keeps track of all
+// the issues resolved by the rendered entries.
+type issueLinker struct {
+	ctx      *context.Context
+	trackers []issueTracker
+	resolved []issueRef
+	seen     map[string]bool
+}
+
+// newIssueLinker returns an issueLinker, or nil if no trackers are configured.
+func newIssueLinker(ctx *context.Context) (*issueLinker, error) {
+	cfg := ctx.Config.Changelog.Issues
+	if len(cfg.Trackers) == 0 {
+		return nil, nil
+	}
+	il := &issueLinker{
+		ctx:  ctx,
+		seen: map[string]bool{},
+	}
+	for _, tracker := range cfg.Trackers {
+		re, err := regexp.Compile(tracker.Regexp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid issue tracker regexp %q: %w", tracker.Regexp, err)
+		}
+		il.trackers = append(il.trackers, issueTracker{re: re, url: tracker.URL})
+	}
+	return il, nil
+}
+
+// render links the issue references of the entry line and appends the ones
+// that are only mentioned in the commit body.
+func (il *issueLinker) render(e entry) (string, error) {
+	if il == nil {
+		return e.line, nil
+	}
+
+	matches, err := il.find(e.line)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	inLine := map[string]bool{}
+	last := 0
+	for _, m := range matches {
+		b.WriteString(e.line[last:m.start])
+		b.WriteString(il.link(m.issueRef))
+		last = m.end
+		inLine[m.id] = true
+	}
+	b.WriteString(e.line[last:])
+
+	bodyMatches, err := il.find(e.commit.Body)
+	if err != nil {
+		return "", err
+	}
+	var extra []string
+	for _, m := range bodyMatches {
+		if inLine[m.id] {
+			continue
+		}
+		inLine[m.id] = true
+		extra = append(extra, il.link(m.issueRef))
+	}
+	if len(extra) > 0 {
+		b.WriteString(" (" + strings.Join(extra, ", ") + ")")
+	}
+	return b.String(), nil
+}
+
+// link records the issue as resolved and returns it as a markdown link.
+func (il *issueLinker) link(ref issueRef) string {
+	if !il.seen[ref.id] {
+		il.seen[ref.id] = true
+		il.resolved = append(il.resolved, ref)
+	}
+	return fmt.Sprintf("[%s](%s)", ref.id, ref.url)
+}
+
+// find returns the non-overlapping issue references in s, in order of appearance.
+func (il *issueLinker) find(s string) ([]issueMatch, error) {
+	var matches []issueMatch
+	for _, tracker := range il.trackers {
+		for _, loc := range tracker.re.FindAllStringSubmatchIndex(s, -1) {
+			m, err := il.match(tracker, s, loc)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+
+	var result []issueMatch
+	end := -1
+	for _, m := range matches {
+		if m.start < end {
+			continue // overlaps a reference of another tracker.
+		}
+		result = append(result, m)
+		end = m.end
+	}
+	return result, nil
+}
+
+// match builds the issueMatch of a single regexp match. The ID is the "id"
+// group if any, otherwise the first group, otherwise the whole match.
+func (il *issueLinker) match(tracker issueTracker, s string, loc []int) (issueMatch, error) {
+	fields := tmpl.Fields{}
+	idx := 0
+	for i, name := range tracker.re.SubexpNames() {
+		if i == 0 || loc[2*i] < 0 {
+			continue
+		}
+		if name != "" {
+			fields[name] = s[loc[2*i]:loc[2*i+1]]
+		}
+		if name == issueIDGroup || idx == 0 {
+			idx = i
+		}
+	}
+	start, end := loc[2*idx], loc[2*idx+1]
+	id := s[start:end]
+	fields["ID"] = id
+
+	url, err := tmpl.New(il.ctx).WithExtraFields(fields).Apply(tracker.url)
+	if err != nil {
+		return issueMatch{}, fmt.Errorf("failed to apply template to issue url %q: %w", tracker.url, err)
+	}
+	return issueMatch{
+		issueRef: issueRef{id: id, url: url},
+		start:    start,
+		end:      end,
+	}, nil
+}
+
+// section returns the list of resolved issues, or nil if disabled or empty.
+func (il *issueLinker) section() []string {
+	if il == nil || il.ctx.Config.Changelog.Issues.Title == "" || len(il.resolved) == 0 {
+		return nil
+	}
+	result := []string{title(il.ctx.Config.Changelog.Issues.Title, 3)}
+	for _, ref := range il.resolved {
+		result = append(result, li+fmt.Sprintf("[%s](%s)", ref.id, ref.url))
+	}
+	return result
+}
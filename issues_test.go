@@ -0,0 +1,116 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+	"github.com/varrcan/generate-pretty-changelog/pkg/git"
+)
+
+const issuesConfig = `
+changelog:
+  issues:
+    title: Resolved issues
+    trackers:
+      - regexp: '\b[A-Z][A-Z0-9]+-\d+\b'
+        url: 'https://acme.atlassian.net/browse/{{ .ID }}'
+      - regexp: '#(\d+)'
+        url: 'https://github.com/acme/app/issues/{{ .ID }}'
+      - regexp: '(?P<id>(?P<repo>[\w.-]+/[\w.-]+)#(?P<number>\d+))'
+        url: 'https://github.com/{{ .repo }}/issues/{{ .number }}'
+      - regexp: '(?P<project>[a-z]+)!(?P<id>\d+)'
+        url: 'https://gitlab.example.com/{{ .project }}/-/merge_requests/{{ .ID }}'
+`
+
+func TestIssueLinkerRender(t *testing.T) {
+	il, err := newIssueLinker(context.New(yamlConfig(t, issuesConfig)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		line, body string
+		want       string
+	}{
+		{
+			line: "PROJ-12 fix: crash #3",
+			want: "[PROJ-12](https://acme.atlassian.net/browse/PROJ-12) fix: crash #[3](https://github.com/acme/app/issues/3)",
+		},
+		{
+			// the cross-repository reference starts first, the #4 inside it is not linked again.
+			line: "fix: crash in acme/lib#4",
+			want: "fix: crash in [acme/lib#4](https://github.com/acme/lib/issues/4)",
+		},
+		{
+			// references only found in the body are appended, once.
+			line: "fix: logout #3",
+			body: "Also fixes #3, PROJ-7 and PROJ-7 again.",
+			want: "fix: logout #[3](https://github.com/acme/app/issues/3) ([PROJ-7](https://acme.atlassian.net/browse/PROJ-7))",
+		},
+		{
+			// the id group wins over the first group.
+			line: "feat: search (web!5)",
+			want: "feat: search (web![5](https://gitlab.example.com/web/-/merge_requests/5))",
+		},
+		{
+			line: "docs: readme",
+			want: "docs: readme",
+		},
+	} {
+		got, err := il.render(entry{commit: git.Commit{Body: tt.body}, line: tt.line})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("render(%q):\ngot  %q\nwant %q", tt.line, got, tt.want)
+		}
+	}
+
+	want := []string{
+		"### Resolved issues",
+		"* [PROJ-12](https://acme.atlassian.net/browse/PROJ-12)",
+		"* [3](https://github.com/acme/app/issues/3)",
+		"* [acme/lib#4](https://github.com/acme/lib/issues/4)",
+		"* [PROJ-7](https://acme.atlassian.net/browse/PROJ-7)",
+		"* [5](https://gitlab.example.com/web/-/merge_requests/5)",
+	}
+	if got := il.section(); !slices.Equal(got, want) {
+		t.Errorf("section:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestIssueLinkerSection(t *testing.T) {
+	cfg := yamlConfig(t, issuesConfig)
+	cfg.Changelog.Issues.Title = ""
+	il, err := newIssueLinker(context.New(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := il.render(entry{line: "fix: crash #3"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := il.section(); got != nil {
+		t.Errorf("section without title = %q", got)
+	}
+
+	il, err = newIssueLinker(context.New(yamlConfig(t, issuesConfig)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := il.section(); got != nil {
+		t.Errorf("section without references = %q", got)
+	}
+}
+
+func TestIssueLinkerInvalid(t *testing.T) {
+	cfg := yamlConfig(t, "changelog:\n  issues:\n    trackers:\n      - regexp: '(PROJ'\n")
+	if _, err := newIssueLinker(context.New(cfg)); err == nil {
+		t.Error("invalid regexp: no error")
+	}
+
+	var none *issueLinker
+	if got, _ := none.render(entry{line: "fix: crash #3"}); got != "fix: crash #3" {
+		t.Errorf("no trackers: %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+	"github.com/varrcan/generate-pretty-changelog/pkg/git"
+	"github.com/varrcan/generate-pretty-changelog/pkg/tmpl"
+)
+
+const (
+	providerGitHub = "github"
+	providerGitLab = "gitlab"
+)
+
+var (
+	issueRefRe   = regexp.MustCompile(`(^|[\s(])([#!])(\d+)\b`)
+	mentionRefRe = regexp.MustCompile(`(^|[\s(])@([a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\[bot\])?)`)
+)
+
+// linker turns commit SHAs, issue/PR numbers and user mentions into links.
+type linker struct {
+	provider string
+	// repoURL is the web URL of the repository, without a trailing slash.
+	repoURL string
+	// hostURL is the web URL of the host, without a trailing slash.
+	hostURL string
+}
+
+// newLinker returns a linker for the current repository, or nil if links are disabled.
+func newLinker(ctx *context.Context) (*linker, error) {
+	if !ctx.Config.Changelog.Links.Enabled {
+		return nil, nil
+	}
+	return newRepoLinker(ctx)
+}
+
+// newRepoLinker returns a linker for the current repository.
+func newRepoLinker(ctx *context.Context) (*linker, error) {
+	cfg := ctx.Config.Changelog.Links
+	repoURL, err := tmpl.New(ctx).Apply(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template to links.url: %w", err)
+	}
+	var host string
+	if repoURL == "" {
+		repo, err := git.ExtractRepoFromConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := repo.CheckSCM(); err != nil {
+			return nil, err
+		}
+		host = repo.Host
+		repoURL = fmt.Sprintf("https://%s/%s/%s", repo.Host, repo.Owner, repo.Name)
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(repoURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid links.url %q: %w", repoURL, err)
+	}
+	if host == "" {
+		host = u.Hostname()
+	}
+
+	provider := cfg.Provider
+	switch provider {
+	case providerGitHub, providerGitLab:
+	case "":
+		provider = providerGitHub
+		if strings.Contains(host, providerGitLab) {
+			provider = providerGitLab
+		}
+	default:
+		return nil, fmt.Errorf("invalid links.provider: %q", provider)
+	}
+
+	return &linker{
+		provider: provider,
+		repoURL:  u.String(),
+		hostURL:  fmt.Sprintf("%s://%s", u.Scheme, u.Host),
+	}, nil
+}
+
+// commitURL returns the URL of a commit.
+func (l *linker) commitURL(sha string) string {
+	if l.provider == providerGitLab {
+		return l.repoURL + "/-/commit/" + sha
+	}
+	return l.repoURL + "/commit/" + sha
+}
+
+// compareURL returns the URL of the diff between two refs.
+func (l *linker) compareURL(prev, current string) string {
+	if l.provider == providerGitLab {
+		return l.repoURL + "/-/compare/" + prev + "..." + current
+	}
+	return l.repoURL + "/compare/" + prev + "..." + current
+}
+
+// issueURL returns the URL of an issue or pull/merge request.
+func (l *linker) issueURL(sigil, number string) string {
+	if l.provider == providerGitLab {
+		if sigil == "!" {
+			return l.repoURL + "/-/merge_requests/" + number
+		}
+		return l.repoURL + "/-/issues/" + number
+	}
+	// GitHub redirects issue URLs to pull requests when needed.
+	return l.repoURL + "/issues/" + number
+}
+
+// userURL returns the profile URL of a user.
+func (l *linker) userURL(login string) string {
+	if bot, ok := strings.CutSuffix(login, "[bot]"); ok && l.provider == providerGitHub {
+		return l.hostURL + "/apps/" + bot
+	}
+	return l.hostURL + "/" + login
+}
+
+// render links the SHA, issue/PR references and mentions of an entry. sha
+// is the commit SHA the line starts with, including its separator, or empty
+// when the line has none.
+func (l *linker) render(sha, msg string) string {
+	if l == nil {
+		return sha + msg
+	}
+	if sha == "" {
+		return l.references(msg)
+	}
+
+	commit, sep := strings.TrimSpace(sha), ""
+	if c, ok := strings.CutSuffix(commit, ":"); ok {
+		commit, sep = c, ":"
+	}
+	return fmt.Sprintf("[%s](%s)%s %s", commit, l.commitURL(commit), sep, l.references(msg))
+}
+
+// references links issue/PR references and mentions.
+func (l *linker) references(s string) string {
+	s = issueRefRe.ReplaceAllStringFunc(s, func(ref string) string {
+		m := issueRefRe.FindStringSubmatch(ref)
+		if m[2] == "!" && l.provider != providerGitLab {
+			return ref
+		}
+		return fmt.Sprintf("%s[%s%s](%s)", m[1], m[2], m[3], l.issueURL(m[2], m[3]))
+	})
+	return mentionRefRe.ReplaceAllStringFunc(s, func(ref string) string {
+		m := mentionRefRe.FindStringSubmatch(ref)
+		return fmt.Sprintf("%s[@%s](%s)", m[1], m[2], l.userURL(m[2]))
+	})
+}
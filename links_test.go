@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/git"
+)
+
+func TestLinkerRender(t *testing.T) {
+	links := &linker{
+		provider: providerGitHub,
+		repoURL:  "https://github.com/acme/app",
+		hostURL:  "https://github.com",
+	}
+	for name, tt := range map[string]struct {
+		sha    string
+		line   string
+		abbrev int
+		want   string
+	}{
+		"git": {
+			sha:  "abc1234",
+			line: "abc1234 fix: crash (#12)",
+			want: "* [abc1234](https://github.com/acme/app/commit/abc1234) fix: crash ([#12](https://github.com/acme/app/issues/12))",
+		},
+		"github": {
+			sha:  "abc1234",
+			line: "abc1234: fix: crash (@jane)",
+			want: "* [abc1234](https://github.com/acme/app/commit/abc1234): fix: crash ([@jane](https://github.com/jane))",
+		},
+		"sha removed": {
+			sha:    "abc1234",
+			line:   "added support for x",
+			abbrev: -1,
+			want:   "* added support for x",
+		},
+		"no commit": {
+			line: "deface the page",
+			want: "* deface the page",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := entryRenderer{links: links, abbrev: tt.abbrev}
+			got, err := r.item(entry{commit: git.Commit{SHA: tt.sha}, line: tt.line}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,16 @@
 package main
 
 import (
+	stdctx "context"
 	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/sethvargo/go-githubactions"
 
@@ -11,66 +19,157 @@ import (
 	"github.com/varrcan/generate-pretty-changelog/pkg/git"
 )
 
-//go:embed changelog.yaml
+//go:embed changelog.yaml presets/*.yaml
 var configFile embed.FS
 
+// defaultTimeout bounds the whole run when the timeout input is empty.
+const defaultTimeout = 30 * time.Minute
+
 func main() {
 	// forwarding file variable to package
 	config.ChangelogFile = configFile
 
-	configPath := githubactions.GetInput("config")
-	if configPath == "" {
-		configPath = "embed"
+	// errors fail the workflow step.
+	if err := run(); err != nil {
+		githubactions.Fatalf("%s", err)
 	}
+}
+
+// run generates the changelog as configured by the action inputs.
+func run() error {
+	configPath := githubactions.GetInput("config")
+	preset := githubactions.GetInput("preset")
 
-	cfg, _ := loadConfig(configPath)
-	ctx := context.New(cfg)
+	cfg, err := loadConfig(configPath, preset)
+	if err != nil {
+		return err
+	}
+	timeout, err := parseTimeout(githubactions.GetInput("timeout"))
+	if err != nil {
+		return err
+	}
+	// cancel on SIGINT/SIGTERM so git and the API requests stop right away.
+	sigCtx, stop := signal.NotifyContext(stdctx.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	timeoutCtx, cancel := stdctx.WithTimeout(sigCtx, timeout)
+	defer cancel()
+	ctx := context.Wrap(timeoutCtx, cfg)
 
 	use := githubactions.GetInput("use")
 	if use != "" {
 		ctx.Config.Changelog.Use = use
 	}
 
-	if ctx.Config.Changelog.Use == "github" {
+	if ctx.Config.Changelog.Use == useGitHub || ctx.Config.Changelog.Use == useGitHubGraphQL {
+		app, err := gitHubApp()
+		if err != nil {
+			return err
+		}
+		ctx.GitHubApp = app
+
 		token := githubactions.GetInput("token")
-		if token == "" {
-			fmt.Println("token is required for use=github")
-			return
+		if token == "" && app.ID == 0 {
+			return fmt.Errorf("token or app_id is required for use=%s", ctx.Config.Changelog.Use)
 		}
 		ctx.Token = token
 	}
 
-	err := git.Run(ctx)
-	if err != nil {
-		fmt.Println(err)
-		return
+	if err := git.Run(ctx); err != nil {
+		return err
 	}
-	err = generate(ctx)
-	if err != nil {
-		fmt.Println(err)
-		return
+	if err := generate(ctx); err != nil {
+		return err
 	}
 
 	fmt.Println(ctx.ReleaseNotes)
 	if ctx.ReleaseNotes != "" {
 		githubactions.SetOutput("changelog", ctx.ReleaseNotes)
 	}
+	return nil
 }
 
-func loadConfig(path string) (config.Config, error) {
-	p, path, err := loadConfigCheck(path)
-	return p, err
+// parseTimeout parses the timeout input, defaulting to defaultTimeout.
+func parseTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(s)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout: %q", s)
+	}
+	return timeout, nil
 }
 
-func loadConfigCheck(path string) (config.Config, string, error) {
-	if path == "embed" {
-		p, err := config.LoadEmbed()
-		return p, path, err
+// gitHubApp returns the GitHub App credentials from the inputs, if any.
+func gitHubApp() (context.GitHubApp, error) {
+	var app context.GitHubApp
+	id := githubactions.GetInput("app_id")
+	if id == "" {
+		return app, nil
+	}
+
+	var err error
+	app.ID, err = strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return app, fmt.Errorf("invalid app_id %q: %w", id, err)
+	}
+	installationID := githubactions.GetInput("app_installation_id")
+	app.InstallationID, err = strconv.ParseInt(installationID, 10, 64)
+	if err != nil {
+		return app, fmt.Errorf("invalid app_installation_id %q: %w", installationID, err)
+	}
+	app.PrivateKey = githubactions.GetInput("app_private_key")
+	if app.PrivateKey == "" {
+		return app, fmt.Errorf("app_private_key is required with app_id")
+	}
+	return app, nil
+}
+
+// configCandidates are the conventional config locations, in lookup order.
+var configCandidates = []struct {
+	path string
+	load func(string) (config.Config, error)
+}{
+	{".github/changelog.yaml", config.Load},
+	{".github/changelog.yml", config.Load},
+	{".changelog.yaml", config.Load},
+	{".changelog.yml", config.Load},
+	{"changelog.yaml", config.Load},
+	{"changelog.yml", config.Load},
+	{".goreleaser.yaml", config.LoadGoReleaser},
+	{".goreleaser.yml", config.LoadGoReleaser},
+}
+
+func loadConfig(path, preset string) (config.Config, error) {
+	p, path, err := loadConfigCheck(path, preset)
+	if err != nil {
+		return p, err
+	}
+	fmt.Printf("using config: %s\n", path)
+	return p, nil
+}
+
+func loadConfigCheck(path, preset string) (config.Config, string, error) {
+	if path != "" && preset != "" {
+		return config.Config{}, "", fmt.Errorf("config %s and preset %s can't be used together", path, preset)
 	}
 	if path != "" {
 		p, err := config.Load(path)
 		return p, path, err
 	}
+	if preset != "" {
+		p, err := config.LoadPreset(preset)
+		return p, "preset " + preset, err
+	}
+	for _, candidate := range configCandidates {
+		p, err := candidate.load(candidate.path)
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, config.ErrNoChangelogSection) {
+			continue
+		}
+		return p, candidate.path, err
+	}
 
-	return config.Config{}, "", nil
+	// none of the known config files exist, so fall back to the embedded one.
+	p, err := config.LoadEmbed()
+	return p, "built-in", err
 }
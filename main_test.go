@@ -0,0 +1,140 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/config"
+)
+
+func TestPresets(t *testing.T) {
+	config.ChangelogFile = configFile
+
+	presets := config.Presets()
+	for _, name := range []string{"angular", "conventional", "gitmoji", "jira", "keepachangelog"} {
+		if !strings.Contains(strings.Join(presets, " "), name) {
+			t.Errorf("preset %s missing from %v", name, presets)
+		}
+	}
+	for _, name := range presets {
+		cfg, err := config.LoadPreset(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(cfg.Changelog.Groups) == 0 && len(cfg.Changelog.Filters.Include) == 0 {
+			t.Errorf("%s: neither groups nor filters", name)
+		}
+	}
+
+	_, err := config.LoadPreset("semantic")
+	if err == nil || !strings.Contains(err.Error(), "available presets: "+strings.Join(presets, ", ")) {
+		t.Errorf("unknown preset: err = %v", err)
+	}
+}
+
+func TestLoadConfigPresetConflict(t *testing.T) {
+	config.ChangelogFile = configFile
+
+	if _, _, err := loadConfigCheck("changelog.yaml", "gitmoji"); err == nil {
+		t.Error("config and preset: no error")
+	}
+	if _, name, err := loadConfigCheck("", "gitmoji"); err != nil || name != "preset gitmoji" {
+		t.Errorf("preset: %q, %v", name, err)
+	}
+}
+
+// chdir changes the working directory for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+// captureStdout returns what fn prints.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	fn()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestConfigDiscovery(t *testing.T) {
+	config.ChangelogFile = configFile
+
+	const section = "changelog:\n  sort: asc\n"
+	for name, tt := range map[string]struct {
+		files map[string]string
+		want  string
+	}{
+		"none": {want: "built-in"},
+		"root": {
+			files: map[string]string{"changelog.yml": section, ".goreleaser.yaml": section},
+			want:  "changelog.yml",
+		},
+		"dotfile over root": {
+			files: map[string]string{"changelog.yaml": section, ".changelog.yml": section},
+			want:  ".changelog.yml",
+		},
+		".github over all": {
+			files: map[string]string{".github/changelog.yml": section, ".changelog.yaml": section, "changelog.yaml": section},
+			want:  ".github/changelog.yml",
+		},
+		"yaml over yml": {
+			files: map[string]string{".github/changelog.yml": section, ".github/changelog.yaml": section},
+			want:  ".github/changelog.yaml",
+		},
+		"goreleaser": {
+			files: map[string]string{".goreleaser.yaml": section},
+			want:  ".goreleaser.yaml",
+		},
+		"goreleaser without changelog section": {
+			files: map[string]string{".goreleaser.yaml": "project_name: app\n", ".goreleaser.yml": section},
+			want:  ".goreleaser.yml",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			chdir(t, t.TempDir())
+			for file, src := range tt.files {
+				if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(src), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var cfg config.Config
+			var err error
+			out := captureStdout(t, func() {
+				cfg, err = loadConfig("", "")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "using config: " + tt.want + "\n"; out != want {
+				t.Errorf("log = %q, want %q", out, want)
+			}
+			if tt.want != "built-in" && cfg.Changelog.Sort != "asc" {
+				t.Errorf("sort = %q, %s wasn't loaded", cfg.Changelog.Sort, tt.want)
+			}
+		})
+	}
+}
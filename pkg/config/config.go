@@ -1,46 +1,174 @@
 package config
 
 import (
+	"bytes"
 	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
 // ChangelogFile embed config file.
 var ChangelogFile embed.FS
 
+// presetsDir is the directory inside ChangelogFile holding the built-in presets.
+const presetsDir = "presets"
+
+// Supported config file formats.
+const (
+	formatYAML = "yaml"
+	formatJSON = "json"
+	formatTOML = "toml"
+)
+
 // filters config.
 type filters struct {
-	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
-	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	Include  []string       `yaml:"include,omitempty" json:"include,omitempty" toml:"include,omitempty"`
+	Exclude  []string       `yaml:"exclude,omitempty" json:"exclude,omitempty" toml:"exclude,omitempty"`
+	Body     bodyFilters    `yaml:"body,omitempty" json:"body,omitempty" toml:"body,omitempty"`
+	Trailers trailerFilters `yaml:"trailers,omitempty" json:"trailers,omitempty" toml:"trailers,omitempty"`
+	Authors  authorFilters  `yaml:"authors,omitempty" json:"authors,omitempty" toml:"authors,omitempty"`
+}
+
+// authorFilters match the author login, name or email.
+type authorFilters struct {
+	Include []string `yaml:"include,omitempty" json:"include,omitempty" toml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty" toml:"exclude,omitempty"`
+}
+
+// bots config.
+type bots struct {
+	// Authors are regexps matching the login, name or email of bots.
+	Authors  []string `yaml:"authors,omitempty" json:"authors,omitempty" toml:"authors,omitempty"`
+	Collapse bool     `yaml:"collapse,omitempty" json:"collapse,omitempty" toml:"collapse,omitempty"`
+	// Text of the entry summarizing the bot commits.
+	Text string `yaml:"text,omitempty" json:"text,omitempty" toml:"text,omitempty"`
+}
+
+// bodyFilters match the commit body.
+type bodyFilters struct {
+	Include []string `yaml:"include,omitempty" json:"include,omitempty" toml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty" toml:"exclude,omitempty"`
+}
+
+// trailerFilters match the value of commit trailers, by trailer key.
+type trailerFilters struct {
+	Include map[string]string `yaml:"include,omitempty" json:"include,omitempty" toml:"include,omitempty"`
+	Exclude map[string]string `yaml:"exclude,omitempty" json:"exclude,omitempty" toml:"exclude,omitempty"`
 }
 
 // changelog Config.
 type changelog struct {
-	Filters filters          `yaml:"filters,omitempty" json:"filters,omitempty"`
-	Sort    string           `yaml:"sort,omitempty" json:"sort,omitempty" jsonschema:"enum=asc,enum=desc,enum=,default="`
-	Use     string           `yaml:"use,omitempty" json:"use,omitempty" jsonschema:"enum=provider,enum=github,enum=github-native,enum=gitlab,default=provider"`
-	Groups  []changelogGroup `yaml:"groups,omitempty" json:"groups,omitempty"`
-	Abbrev  int              `yaml:"abbrev,omitempty" json:"abbrev,omitempty"`
+	Filters      filters          `yaml:"filters,omitempty" json:"filters,omitempty" toml:"filters,omitempty"`
+	Sort         string           `yaml:"sort,omitempty" json:"sort,omitempty" toml:"sort,omitempty" jsonschema:"enum=asc,enum=desc,enum=message,enum=date,enum=author,enum=scope,enum=pr,enum=topo,enum=,default="`
+	Use          string           `yaml:"use,omitempty" json:"use,omitempty" toml:"use,omitempty" jsonschema:"enum=provider,enum=github,enum=github-graphql,enum=github-native,enum=gitlab,default=provider"`
+	Groups       []changelogGroup `yaml:"groups,omitempty" json:"groups,omitempty" toml:"groups,omitempty"`
+	Abbrev       int              `yaml:"abbrev,omitempty" json:"abbrev,omitempty" toml:"abbrev,omitempty"`
+	Output       string           `yaml:"output,omitempty" json:"output,omitempty" toml:"output,omitempty"`
+	Links        links            `yaml:"links,omitempty" json:"links,omitempty" toml:"links,omitempty"`
+	Header       string           `yaml:"header,omitempty" json:"header,omitempty" toml:"header,omitempty"`
+	Footer       string           `yaml:"footer,omitempty" json:"footer,omitempty" toml:"footer,omitempty"`
+	Issues       issues           `yaml:"issues,omitempty" json:"issues,omitempty" toml:"issues,omitempty"`
+	Replace      []replace        `yaml:"replace,omitempty" json:"replace,omitempty" toml:"replace,omitempty"`
+	Capitalize   bool             `yaml:"capitalize,omitempty" json:"capitalize,omitempty" toml:"capitalize,omitempty"`
+	Dedupe       bool             `yaml:"dedupe,omitempty" json:"dedupe,omitempty" toml:"dedupe,omitempty"`
+	DropReverted bool             `yaml:"drop_reverted,omitempty" json:"drop_reverted,omitempty" toml:"drop_reverted,omitempty"`
+	ReleaseNote  string           `yaml:"release_note,omitempty" json:"release_note,omitempty" toml:"release_note,omitempty"`
+	Bots         bots             `yaml:"bots,omitempty" json:"bots,omitempty" toml:"bots,omitempty"`
+}
+
+// replace rewrites the text of the entries matching Regexp.
+type replace struct {
+	Regexp      string `yaml:"regexp,omitempty" json:"regexp,omitempty" toml:"regexp,omitempty"`
+	Replacement string `yaml:"replacement,omitempty" json:"replacement,omitempty" toml:"replacement,omitempty"`
+}
+
+// issues config.
+type issues struct {
+	Trackers []issueTracker `yaml:"trackers,omitempty" json:"trackers,omitempty" toml:"trackers,omitempty"`
+	Title    string         `yaml:"title,omitempty" json:"title,omitempty" toml:"title,omitempty"`
+}
+
+// issueTracker holds the pattern of issue references and the URL they link to.
+type issueTracker struct {
+	Regexp string `yaml:"regexp,omitempty" json:"regexp,omitempty" toml:"regexp,omitempty"`
+	URL    string `yaml:"url,omitempty" json:"url,omitempty" toml:"url,omitempty"`
+}
+
+// links config.
+type links struct {
+	Enabled  bool   `yaml:"enabled,omitempty" json:"enabled,omitempty" toml:"enabled,omitempty"`
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty" toml:"provider,omitempty" jsonschema:"enum=github,enum=gitlab,enum=,default="`
+	URL      string `yaml:"url,omitempty" json:"url,omitempty" toml:"url,omitempty"`
 }
 
 // changelogGroup holds the grouping criteria for the changelog.
 type changelogGroup struct {
-	Title  string `yaml:"title,omitempty" json:"title,omitempty"`
-	Regexp string `yaml:"regexp,omitempty" json:"regexp,omitempty"`
-	Order  int    `yaml:"order,omitempty" json:"order,omitempty"`
+	Title  string `yaml:"title,omitempty" json:"title,omitempty" toml:"title,omitempty"`
+	Regexp string `yaml:"regexp,omitempty" json:"regexp,omitempty" toml:"regexp,omitempty"`
+	Order  int    `yaml:"order,omitempty" json:"order,omitempty" toml:"order,omitempty"`
+	Scopes string `yaml:"scopes,omitempty" json:"scopes,omitempty" toml:"scopes,omitempty" jsonschema:"enum=heading,enum=bold,enum=,default="`
+	// Exclusive groups keep their entries out of the groups matched after them, defaults to true.
+	Exclusive *bool `yaml:"exclusive,omitempty" json:"exclusive,omitempty" toml:"exclusive,omitempty"`
+	// Priority decides the matching order, higher first, independent of the display Order.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty" toml:"priority,omitempty"`
+	// Sort overrides the changelog sort mode for the entries of the group.
+	Sort string `yaml:"sort,omitempty" json:"sort,omitempty" toml:"sort,omitempty"`
+}
+
+// gitHubURLs holds the URLs of a GitHub Enterprise Server instance.
+type gitHubURLs struct {
+	API           string `yaml:"api,omitempty" json:"api,omitempty" toml:"api,omitempty"`
+	Upload        string `yaml:"upload,omitempty" json:"upload,omitempty" toml:"upload,omitempty"`
+	SkipTLSVerify bool   `yaml:"skip_tls_verify,omitempty" json:"skip_tls_verify,omitempty" toml:"skip_tls_verify,omitempty"`
+}
+
+// rateLimit controls how long GitHub API requests wait for rate limits to reset.
+type rateLimit struct {
+	// MaxWait is a duration such as 10m, defaults to 10 minutes.
+	MaxWait string `yaml:"max_wait,omitempty" json:"max_wait,omitempty" toml:"max_wait,omitempty"`
+}
+
+// cache holds the on-disk cache of the GitHub API responses.
+type cache struct {
+	// Dir enables the cache, it can be saved between runs with actions/cache.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty" toml:"dir,omitempty"`
+}
+
+// gitConfig selects how the git repository is read.
+type gitConfig struct {
+	// Backend is cli to run the git binary, or go-git to read the repository without it.
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty" toml:"backend,omitempty" jsonschema:"enum=cli,enum=go-git,default=cli"`
+	// Shallow is fetch to fetch the history and tags of shallow clones, fail to stop, or ignore.
+	Shallow string `yaml:"shallow,omitempty" json:"shallow,omitempty" toml:"shallow,omitempty" jsonschema:"enum=fetch,enum=fail,enum=ignore,default=fetch"`
 }
 
 // Config includes all configuration.
 type Config struct {
-	Env       []string  `yaml:"env,omitempty" json:"env,omitempty"`
-	Changelog changelog `yaml:"changelog,omitempty" json:"changelog,omitempty"`
+	Env        []string   `yaml:"env,omitempty" json:"env,omitempty" toml:"env,omitempty"`
+	GitHubURLs gitHubURLs `yaml:"github_urls,omitempty" json:"github_urls,omitempty" toml:"github_urls,omitempty"`
+	RateLimit  rateLimit  `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty" toml:"rate_limit,omitempty"`
+	Cache      cache      `yaml:"cache,omitempty" json:"cache,omitempty" toml:"cache,omitempty"`
+	Git        gitConfig  `yaml:"git,omitempty" json:"git,omitempty" toml:"git,omitempty"`
+	Changelog  changelog  `yaml:"changelog,omitempty" json:"changelog,omitempty" toml:"changelog,omitempty"`
 }
 
 // Load config file.
 func Load(file string) (config Config, err error) {
+	if isGoReleaser(file) {
+		return LoadGoReleaser(file)
+	}
 	f, err := os.Open(file) // #nosec
 	if err != nil {
 		return
@@ -51,20 +179,89 @@ func Load(file string) (config Config, err error) {
 			return
 		}
 	}(f)
-	return loadReader(f)
+	format, err := formatOf(file)
+	if err != nil {
+		return config, err
+	}
+	config, err = loadReader(f, format, file)
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", file, err)
+	}
+	return config, nil
+}
+
+// formatOf detects the config format by the file extension.
+func formatOf(file string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".yaml", ".yml", "":
+		return formatYAML, nil
+	case ".json":
+		return formatJSON, nil
+	case ".toml":
+		return formatTOML, nil
+	default:
+		return "", fmt.Errorf("unsupported config format %q, use .yaml, .yml, .json or .toml", ext)
+	}
 }
 
-// loadReader config via io.Reader.
-func loadReader(fd io.Reader) (config Config, err error) {
+// loadReader config via io.Reader, warning about the unknown fields.
+func loadReader(fd io.Reader, format, name string) (config Config, err error) {
 	data, err := io.ReadAll(fd)
 	if err != nil {
 		return config, err
 	}
 
-	err = yaml.Unmarshal(data, &config)
+	unknown, err := unmarshal(data, format, &config)
+	for _, field := range unknown {
+		fmt.Printf("%s: unknown field %q is ignored\n", name, field)
+	}
 	return config, err
 }
 
+// yamlUnknownFieldRe matches the unknown field errors of strict YAML decoding.
+var yamlUnknownFieldRe = regexp.MustCompile(`field (\S+) not found in type`)
+
+// unmarshal decodes data in the given format and returns the unknown fields,
+// which are ignored the same way in every format.
+func unmarshal(data []byte, format string, config *Config) (unknown []string, err error) {
+	switch format {
+	case formatJSON:
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if field, ok := strings.CutPrefix(fmt.Sprint(dec.Decode(&Config{})), "json: unknown field "); ok {
+			unknown = append(unknown, strings.Trim(field, `"`))
+		}
+		return unknown, nil
+	case formatTOML:
+		md, err := toml.Decode(string(data), config)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range md.Undecoded() {
+			unknown = append(unknown, key.String())
+		}
+		return unknown, nil
+	default:
+		if err := yaml.Unmarshal(data, config); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		var typeErr *yaml.TypeError
+		if errors.As(dec.Decode(&Config{}), &typeErr) {
+			for _, msg := range typeErr.Errors {
+				if m := yamlUnknownFieldRe.FindStringSubmatch(msg); m != nil {
+					unknown = append(unknown, m[1])
+				}
+			}
+		}
+		return unknown, nil
+	}
+}
+
 // LoadEmbed config via embed.FS
 func LoadEmbed() (config Config, err error) {
 	data, err := ChangelogFile.ReadFile("changelog.yaml")
@@ -72,6 +269,42 @@ func LoadEmbed() (config Config, err error) {
 		return config, err
 	}
 
-	err = yaml.Unmarshal(data, &config)
+	err = unmarshalBuiltin(data, &config)
 	return config, err
 }
+
+// LoadPreset loads one of the built-in presets via embed.FS
+func LoadPreset(name string) (config Config, err error) {
+	data, err := ChangelogFile.ReadFile(path.Join(presetsDir, name+".yaml"))
+	if err != nil {
+		return config, fmt.Errorf("unknown preset %q, available presets: %s", name, strings.Join(Presets(), ", "))
+	}
+
+	err = unmarshalBuiltin(data, &config)
+	return config, err
+}
+
+// unmarshalBuiltin decodes a built-in config, which has no unknown fields.
+func unmarshalBuiltin(data []byte, config *Config) error {
+	unknown, err := unmarshal(data, formatYAML, config)
+	if err == nil && len(unknown) > 0 {
+		err = fmt.Errorf("unknown field %q", unknown[0])
+	}
+	return err
+}
+
+// Presets returns the names of the built-in presets.
+func Presets() []string {
+	entries, err := fs.ReadDir(ChangelogFile, presetsDir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".yaml"); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
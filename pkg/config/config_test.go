@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestLoadFormats(t *testing.T) {
+	want := Config{Changelog: changelog{
+		Use:    "github",
+		Sort:   "asc",
+		Abbrev: -1,
+		Filters: filters{
+			Exclude: []string{"^docs:"},
+		},
+		Groups: []changelogGroup{{Title: "Features", Regexp: "^feat", Order: 1}},
+	}}
+
+	for file, src := range map[string]string{
+		"changelog.yaml": `
+changelog:
+  use: github
+  sort: asc
+  abbrev: -1
+  filters:
+    exclude: ['^docs:']
+  groups:
+    - title: Features
+      regexp: '^feat'
+      order: 1
+`,
+		"changelog.json": `{"changelog": {
+  "use": "github",
+  "sort": "asc",
+  "abbrev": -1,
+  "filters": {"exclude": ["^docs:"]},
+  "groups": [{"title": "Features", "regexp": "^feat", "order": 1}]
+}}`,
+		"changelog.toml": `
+[changelog]
+use = "github"
+sort = "asc"
+abbrev = -1
+
+[changelog.filters]
+exclude = ["^docs:"]
+
+[[changelog.groups]]
+title = "Features"
+regexp = "^feat"
+order = 1
+`,
+	} {
+		t.Run(file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), file)
+			if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalUnknownFields(t *testing.T) {
+	for format, src := range map[string]string{
+		formatYAML: "changelog:\n  sort: asc\n  sorting: desc\n",
+		formatJSON: `{"changelog": {"sort": "asc", "sorting": "desc"}}`,
+		formatTOML: "[changelog]\nsort = \"asc\"\nsorting = \"desc\"\n",
+	} {
+		t.Run(format, func(t *testing.T) {
+			var cfg Config
+			unknown, err := unmarshal([]byte(src), format, &cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Changelog.Sort != "asc" {
+				t.Errorf("sort = %q, the known fields must still load", cfg.Changelog.Sort)
+			}
+			if len(unknown) != 1 || !slices.Contains([]string{"sorting", "changelog.sorting"}, unknown[0]) {
+				t.Errorf("unknown = %q", unknown)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for file, src := range map[string]string{
+		"changelog.yaml": "changelog: [",
+		"changelog.json": `{"changelog": `,
+		"changelog.toml": "[changelog",
+		"changelog.ini":  "[changelog]",
+	} {
+		path := filepath.Join(t.TempDir(), file)
+		if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: no error", file)
+		}
+	}
+}
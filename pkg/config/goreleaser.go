@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoChangelogSection happens when a goreleaser config has no changelog section.
+var ErrNoChangelogSection = errors.New("no changelog section found")
+
+// goreleaserProject is the subset of a goreleaser config this action understands.
+type goreleaserProject struct {
+	Env        []string             `yaml:"env,omitempty"`
+	GitHubURLs gitHubURLs           `yaml:"github_urls,omitempty"`
+	Changelog  *goreleaserChangelog `yaml:"changelog,omitempty"`
+}
+
+// goreleaserChangelog mirrors the goreleaser changelog section.
+type goreleaserChangelog struct {
+	Disable string            `yaml:"disable,omitempty"`
+	Use     string            `yaml:"use,omitempty"`
+	Format  string            `yaml:"format,omitempty"`
+	Sort    string            `yaml:"sort,omitempty"`
+	Abbrev  int               `yaml:"abbrev,omitempty"`
+	Filters filters           `yaml:"filters,omitempty"`
+	Groups  []goreleaserGroup `yaml:"groups,omitempty"`
+}
+
+// goreleaserGroup mirrors a goreleaser changelog group, including nested groups.
+type goreleaserGroup struct {
+	Title  string            `yaml:"title,omitempty"`
+	Regexp string            `yaml:"regexp,omitempty"`
+	Order  int               `yaml:"order,omitempty"`
+	Groups []goreleaserGroup `yaml:"groups,omitempty"`
+}
+
+// isGoReleaser returns true if the file name looks like a goreleaser config.
+func isGoReleaser(file string) bool {
+	name := strings.TrimPrefix(strings.ToLower(filepath.Base(file)), ".")
+	return name == "goreleaser.yaml" || name == "goreleaser.yml"
+}
+
+// LoadGoReleaser loads the changelog section of a goreleaser config file.
+func LoadGoReleaser(file string) (config Config, err error) {
+	data, err := os.ReadFile(file) // #nosec
+	if err != nil {
+		return config, err
+	}
+
+	var project goreleaserProject
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return config, fmt.Errorf("%s: %w", file, err)
+	}
+	if project.Changelog == nil {
+		return config, ErrNoChangelogSection
+	}
+
+	return Config{
+		Env:        project.Env,
+		GitHubURLs: project.GitHubURLs,
+		Changelog:  project.Changelog.convert(file),
+	}, nil
+}
+
+// convert the goreleaser changelog section into our own, warning about the
+// settings that have no equivalent here.
+func (c goreleaserChangelog) convert(file string) changelog {
+	warn := func(format string, a ...any) {
+		fmt.Printf("%s: "+format+"\n", append([]any{file}, a...)...)
+	}
+
+	if c.Disable != "" && c.Disable != "false" {
+		warn("changelog.disable is ignored")
+	}
+	if c.Format != "" {
+		warn("changelog.format is not supported and will be ignored")
+	}
+
+	use := c.Use
+	switch use {
+	case "", "git", "github":
+	case "github-native":
+		warn("changelog.use %q is not supported, using %q", use, "github")
+		use = "github"
+	default:
+		warn("changelog.use %q is not supported, using %q", use, "git")
+		use = "git"
+	}
+
+	groups := make([]changelogGroup, 0, len(c.Groups))
+	for _, group := range c.Groups {
+		if len(group.Groups) > 0 {
+			warn("nested groups of %q are not supported and will be ignored", group.Title)
+		}
+		groups = append(groups, changelogGroup{
+			Title:  group.Title,
+			Regexp: group.Regexp,
+			Order:  group.Order,
+		})
+	}
+
+	return changelog{
+		Filters: c.Filters,
+		Sort:    c.Sort,
+		Use:     use,
+		Groups:  groups,
+		Abbrev:  c.Abbrev,
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what fn prints.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	fn()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+// writeGoReleaser writes a goreleaser config and returns its path.
+func writeGoReleaser(t *testing.T, src string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), ".goreleaser.yaml")
+	if err := os.WriteFile(file, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadGoReleaser(t *testing.T) {
+	file := writeGoReleaser(t, `
+project_name: app
+env:
+  - FOO=bar
+github_urls:
+  api: https://github.example.com/api/v3/
+changelog:
+  use: github
+  sort: desc
+  abbrev: -1
+  filters:
+    exclude:
+      - '^docs:'
+    include:
+      - '^feat'
+      - '^fix'
+  groups:
+    - title: Features
+      regexp: '^feat'
+      order: 0
+    - title: Fixes
+      regexp: '^fix'
+      order: 1
+`)
+
+	var cfg Config
+	var err error
+	out := captureStdout(t, func() {
+		cfg, err = Load(file)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "" {
+		t.Errorf("unexpected warnings: %q", out)
+	}
+
+	want := Config{
+		Env:        []string{"FOO=bar"},
+		GitHubURLs: gitHubURLs{API: "https://github.example.com/api/v3/"},
+		Changelog: changelog{
+			Use:    "github",
+			Sort:   "desc",
+			Abbrev: -1,
+			Filters: filters{
+				Include: []string{"^feat", "^fix"},
+				Exclude: []string{"^docs:"},
+			},
+			Groups: []changelogGroup{
+				{Title: "Features", Regexp: "^feat", Order: 0},
+				{Title: "Fixes", Regexp: "^fix", Order: 1},
+			},
+		},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadGoReleaserUnsupported(t *testing.T) {
+	for name, tt := range map[string]struct {
+		changelog string
+		use       string
+		warning   string
+	}{
+		"disable": {
+			changelog: "disable: true",
+			warning:   "changelog.disable is ignored",
+		},
+		"disable false": {
+			changelog: "disable: false",
+		},
+		"format": {
+			changelog: "format: '{{ .SHA }}: {{ .Message }}'",
+			warning:   "changelog.format is not supported and will be ignored",
+		},
+		"github-native": {
+			changelog: "use: github-native",
+			use:       "github",
+			warning:   `changelog.use "github-native" is not supported, using "github"`,
+		},
+		"gitea": {
+			changelog: "use: gitea",
+			use:       "git",
+			warning:   `changelog.use "gitea" is not supported, using "git"`,
+		},
+		"nested groups": {
+			changelog: "groups:\n    - title: Features\n      groups:\n        - title: UI",
+			warning:   `nested groups of "Features" are not supported and will be ignored`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			file := writeGoReleaser(t, "changelog:\n  "+tt.changelog+"\n")
+
+			var cfg Config
+			var err error
+			out := captureStdout(t, func() {
+				cfg, err = LoadGoReleaser(file)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Changelog.Use != tt.use {
+				t.Errorf("use = %q, want %q", cfg.Changelog.Use, tt.use)
+			}
+			if tt.warning == "" {
+				if out != "" {
+					t.Errorf("unexpected warnings: %q", out)
+				}
+				return
+			}
+			if want := file + ": " + tt.warning + "\n"; out != want {
+				t.Errorf("warning = %q, want %q", out, want)
+			}
+		})
+	}
+}
+
+func TestLoadGoReleaserNoChangelogSection(t *testing.T) {
+	file := writeGoReleaser(t, "project_name: app\n")
+	if _, err := LoadGoReleaser(file); !errors.Is(err, ErrNoChangelogSection) {
+		t.Fatalf("err = %v", err)
+	}
+
+	file = writeGoReleaser(t, "changelog: [")
+	if _, err := LoadGoReleaser(file); err == nil || !strings.HasPrefix(err.Error(), file) {
+		t.Fatalf("err = %v", err)
+	}
+}
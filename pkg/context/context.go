@@ -2,8 +2,10 @@ package context
 
 import (
 	stdctx "context"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/varrcan/generate-pretty-changelog/pkg/config"
 )
@@ -14,6 +16,20 @@ type GitInfo struct {
 	PreviousTag string
 	Commit      string
 	FirstCommit string
+	TagDate     time.Time
+}
+
+// GitHubApp holds the credentials of a GitHub App installation.
+type GitHubApp struct {
+	ID             int64
+	InstallationID int64
+	PrivateKey     string
+}
+
+// GitRunner runs git commands and returns their output, or an error holding
+// their stderr.
+type GitRunner interface {
+	Run(ctx stdctx.Context, env []string, stdin io.Reader, args ...string) (string, error)
 }
 
 // env is the environment variables.
@@ -22,9 +38,14 @@ type env map[string]string
 // Context carries along some data through the pipes.
 type Context struct {
 	stdctx.Context
-	Config       config.Config
-	Env          env
-	Token        string
+	Config    config.Config
+	Env       env
+	Token     string
+	GitHubApp GitHubApp
+	// Dir is the directory of the git repository, the current directory when empty.
+	Dir string
+	// GitRunner runs the git commands, the git CLI in Dir when nil.
+	GitRunner    GitRunner
 	Git          GitInfo
 	ReleaseNotes string
 	Version      string
@@ -35,6 +56,11 @@ func New(config config.Config) *Context {
 	return wrap(stdctx.Background(), config)
 }
 
+// Wrap wraps an existing context.
+func Wrap(ctx stdctx.Context, config config.Config) *Context {
+	return wrap(ctx, config)
+}
+
 // wrap wraps an existing context.
 func wrap(ctx stdctx.Context, config config.Config) *Context {
 	return &Context{
@@ -0,0 +1,85 @@
+package git
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-github/v57/github"
+	"golang.org/x/oauth2"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+// appJWTExpiry is how long the app JWT is valid, GitHub allows at most 10 minutes.
+const appJWTExpiry = 9 * time.Minute
+
+// appTokenSource returns installation access tokens of a GitHub App.
+type appTokenSource struct {
+	ctx            *context.Context
+	client         *github.Client
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+}
+
+// newAppTokenSource returns a token source exchanging a JWT signed with the
+// app private key for installation tokens, refreshed once they expire.
+func newAppTokenSource(ctx *context.Context, app context.GitHubApp) (oauth2.TokenSource, error) {
+	if app.InstallationID == 0 {
+		return nil, fmt.Errorf("installation id is required for GitHub App %d", app.ID)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(app.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of GitHub App %d: %w", app.ID, err)
+	}
+	transport, err := newTransport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client, err := withEnterpriseURLs(ctx, github.NewClient(&http.Client{
+		Transport: transport,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSource(nil, &appTokenSource{
+		ctx:            ctx,
+		client:         client,
+		appID:          app.ID,
+		installationID: app.InstallationID,
+		key:            key,
+	}), nil
+}
+
+// Token returns a new installation access token.
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	signed, err := s.jwt()
+	if err != nil {
+		return nil, err
+	}
+	token, _, err := s.client.WithAuthToken(signed).Apps.CreateInstallationToken(s.ctx, s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create installation token of GitHub App %d: %w", s.appID, err)
+	}
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "Bearer",
+		Expiry:      token.GetExpiresAt().Time,
+	}, nil
+}
+
+// jwt returns a JWT authenticating as the app.
+func (s *appTokenSource) jwt() (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Issuer: strconv.FormatInt(s.appID, 10),
+		// issued in the past to allow for clock drift.
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(appJWTExpiry)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(s.key)
+}
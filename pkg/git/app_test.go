@@ -0,0 +1,104 @@
+package git
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/config"
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+func TestAppTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	var issued int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s", r.Method)
+		}
+		signed, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			t.Fatalf("authorization = %q", r.Header.Get("Authorization"))
+		}
+		var claims jwt.RegisteredClaims
+		if _, err := jwt.ParseWithClaims(signed, &claims, func(*jwt.Token) (any, error) {
+			return &key.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"RS256"})); err != nil {
+			t.Fatalf("invalid JWT: %v", err)
+		}
+		if claims.Issuer != "7" {
+			t.Errorf("iss = %q", claims.Issuer)
+		}
+		if !claims.IssuedAt.Before(time.Now()) {
+			t.Errorf("iat = %v, not in the past", claims.IssuedAt)
+		}
+		if lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time); lifetime > 10*time.Minute {
+			t.Errorf("JWT valid for %v, GitHub allows 10m", lifetime)
+		}
+
+		issued++
+		// the first token expires right away, the second one is reused.
+		expiry := time.Now().Add(time.Second)
+		if issued > 1 {
+			expiry = time.Now().Add(time.Hour)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      fmt.Sprintf("installation-%d", issued),
+			"expires_at": expiry.Format(time.RFC3339),
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var cfg config.Config
+	cfg.GitHubURLs.API = srv.URL + "/api/v3/"
+	ctx := context.New(cfg)
+	ctx.GitHubApp = context.GitHubApp{ID: 7, InstallationID: 42, PrivateKey: string(privateKey)}
+
+	ts, err := tokenSource(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"installation-1", "installation-2", "installation-2"} {
+		token, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.AccessToken != want {
+			t.Errorf("token = %q, want %q", token.AccessToken, want)
+		}
+	}
+	if issued != 2 {
+		t.Errorf("%d tokens issued, want 2", issued)
+	}
+}
+
+func TestAppTokenSourceInvalid(t *testing.T) {
+	ctx := context.New(config.Config{})
+	if _, err := newAppTokenSource(ctx, context.GitHubApp{ID: 7, PrivateKey: "key"}); err == nil {
+		t.Error("missing installation id: no error")
+	}
+	if _, err := newAppTokenSource(ctx, context.GitHubApp{ID: 7, InstallationID: 42, PrivateKey: "key"}); err == nil {
+		t.Error("invalid private key: no error")
+	}
+}
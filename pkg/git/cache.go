@@ -0,0 +1,105 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+)
+
+// cacheTransport caches the GitHub API responses on disk and revalidates them
+// with conditional requests, which don't count against the rate limit.
+type cacheTransport struct {
+	base http.RoundTripper
+	dir  string
+}
+
+func newCacheTransport(base http.RoundTripper, dir string) *cacheTransport {
+	return &cacheTransport{
+		base: base,
+		dir:  dir,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	path := t.path(req)
+	cached := t.load(path, req)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.Header.Get("ETag"))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		if cached != nil {
+			cached.Body.Close()
+		}
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		return cached, nil
+	}
+	if cached != nil {
+		cached.Body.Close()
+	}
+	if resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "" {
+		return t.store(path, resp)
+	}
+	return resp, nil
+}
+
+// path returns the cache file of the request, keyed by its URL.
+func (t *cacheTransport) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept")))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:]))
+}
+
+// load returns the cached response of the request, or nil.
+func (t *cacheTransport) load(path string, req *http.Request) *http.Response {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req)
+	if err != nil || resp.Header.Get("ETag") == "" {
+		return nil
+	}
+	return resp
+}
+
+// store saves the response to the cache and returns it with a fresh body.
+func (t *cacheTransport) store(path string, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	dump, err := httputil.DumpResponse(resp, true)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return resp, nil
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		fmt.Printf("could not create the cache directory: %v\n", err)
+		return resp, nil
+	}
+	if err := os.WriteFile(path, dump, 0o600); err != nil {
+		fmt.Printf("could not cache %s: %v\n", resp.Request.URL, err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,41 @@
+package git
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheTransport(t *testing.T) {
+	var full, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"sha":"abc"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		// a new transport for every run, like separate action runs sharing the directory.
+		client := &http.Client{Transport: newCacheTransport(http.DefaultTransport, dir)}
+		resp, err := client.Get(srv.URL + "/repos/acme/app/compare/v1.0.0...v1.1.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != `{"sha":"abc"}` {
+			t.Fatalf("run %d: %d %q", i, resp.StatusCode, body)
+		}
+	}
+	if full != 1 || notModified != 2 {
+		t.Fatalf("full = %d, not modified = %d", full, notModified)
+	}
+}
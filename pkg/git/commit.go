@@ -0,0 +1,85 @@
+package git
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+// field and record separators of the git log format.
+const (
+	fieldSep  = "\x1f"
+	recordSep = "\x1e"
+)
+
+// Commit holds the details of a commit listed in the changelog.
+type Commit struct {
+	SHA         string
+	Subject     string
+	Body        string
+	Login       string
+	AuthorName  string
+	AuthorEmail string
+	Date        time.Time
+	// PullRequest and Labels are only known with use: github-graphql.
+	PullRequest int
+	Labels      []string
+}
+
+// trailerRe matches a "Key: value" git trailer line.
+var trailerRe = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):\s*(.*)$`)
+
+// Trailers returns the git trailers found in the last paragraph of the body,
+// keyed by lowercase trailer key.
+func (c Commit) Trailers() map[string][]string {
+	paragraphs := strings.Split(strings.TrimSpace(c.Body), "\n\n")
+	trailers := map[string][]string{}
+	var key string
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		line = strings.TrimRight(line, "\r")
+		if key != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			// continuation of a multi-line trailer value.
+			values := trailers[key]
+			values[len(values)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+		m := trailerRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil // not a trailer block.
+		}
+		key = strings.ToLower(m[1])
+		trailers[key] = append(trailers[key], strings.TrimSpace(m[2]))
+	}
+	return trailers
+}
+
+// newCommit builds a Commit from a full commit message.
+func newCommit(sha, message string) Commit {
+	subject, body, _ := strings.Cut(message, "\n")
+	return Commit{
+		SHA:     sha,
+		Subject: strings.TrimSpace(subject),
+		Body:    strings.TrimSpace(body),
+	}
+}
+
+// Log returns the commits of the given revision range, newest first.
+func Log(ctx *context.Context, revs ...string) ([]Commit, error) {
+	repo, err := OpenRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.Log(revs...)
+}
+
+// PatchIDs returns the stable patch ID of the given commits, keyed by full SHA.
+// Commits without changes, like empty commits, are left out.
+func PatchIDs(ctx *context.Context, shas ...string) (map[string]string, error) {
+	repo, err := OpenRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.PatchIDs(shas...)
+}
@@ -0,0 +1,48 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrailers(t *testing.T) {
+	for name, tt := range map[string]struct {
+		body string
+		want map[string][]string
+	}{
+		"empty": {
+			body: "",
+		},
+		"trailers": {
+			body: "Explain the fix.\n\nSigned-off-by: Jane Doe <jane@example.com>\nRelease-Note: Fix the crash\nCo-authored-by: John <john@example.com>\nco-authored-by: Zoe <zoe@example.com>",
+			want: map[string][]string{
+				"signed-off-by":  {"Jane Doe <jane@example.com>"},
+				"release-note":   {"Fix the crash"},
+				"co-authored-by": {"John <john@example.com>", "Zoe <zoe@example.com>"},
+			},
+		},
+		"continuation lines": {
+			body: "Release-Note: Fix the crash\n  on start\n\twith no config\nFixes: #12\r\n",
+			want: map[string][]string{
+				"release-note": {"Fix the crash on start with no config"},
+				"fixes":        {"#12"},
+			},
+		},
+		"last paragraph only": {
+			body: "Release-Note: not a trailer\n\nExplain the fix.",
+		},
+		"not a trailer block": {
+			body: "Explain the fix.\n\nSigned-off-by: Jane Doe <jane@example.com>\nThanks to everyone.",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := Commit{Body: tt.body}.Trailers()
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
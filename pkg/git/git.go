@@ -2,11 +2,14 @@ package git
 
 import (
 	"bytes"
+	stdctx "context"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os/exec"
 	"path"
+	"slices"
 	"strings"
 
 	"github.com/varrcan/generate-pretty-changelog/pkg/context"
@@ -14,24 +17,29 @@ import (
 
 // Run the git command
 func Run(ctx *context.Context) error {
-	if _, err := exec.LookPath("git"); err != nil {
-		return errors.New("git not present in PATH")
+	repo, err := OpenRepository(ctx)
+	if err != nil {
+		return err
 	}
-	info, err := getInfo(ctx)
+	info, err := getInfo(ctx, repo)
 	if err != nil {
 		return err
 	}
 	ctx.Git = info
 	ctx.Version = strings.TrimPrefix(ctx.Git.CurrentTag, "v")
-	return validate(ctx)
+	return validate(ctx, repo)
 }
 
 // ExtractRepoFromConfig gets the repo name from the Git config.
 func ExtractRepoFromConfig(ctx *context.Context) (result Repo, err error) {
-	if !isRepo(ctx) {
-		return result, errors.New("current folder is not a git repository")
+	repo, err := OpenRepository(ctx)
+	if err != nil {
+		return result, err
+	}
+	if !repo.IsRepo() {
+		return result, contextError(ctx, errors.New("current folder is not a git repository"), "git rev-parse")
 	}
-	out, err := clean(Exec(ctx, "ls-remote", "--get-url"))
+	out, err := repo.RemoteURL()
 	if err != nil {
 		return result, fmt.Errorf("no remote configured to list refs from")
 	}
@@ -41,8 +49,11 @@ func ExtractRepoFromConfig(ctx *context.Context) (result Repo, err error) {
 // extractRepoFromURL gets the repo name from the URL
 func extractRepoFromURL(rawurl string) (Repo, error) {
 	s := strings.TrimSuffix(strings.TrimSpace(rawurl), ".git")
+	var host string
 	if strings.Count(s, ":") == 1 {
-		s = s[strings.LastIndex(s, ":")+1:]
+		// scp-like syntax: [user@]host:owner/name
+		host, s, _ = strings.Cut(s, ":")
+		host = host[strings.LastIndex(host, "@")+1:]
 	}
 
 	u, err := url.Parse(s)
@@ -51,6 +62,9 @@ func extractRepoFromURL(rawurl string) (Repo, error) {
 			RawURL: rawurl,
 		}, err
 	}
+	if u.Host != "" {
+		host = u.Hostname()
+	}
 
 	ss := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
 	if len(ss) == 0 || ss[0] == "" {
@@ -62,37 +76,87 @@ func extractRepoFromURL(rawurl string) (Repo, error) {
 	if len(ss) < 2 {
 		return Repo{
 			RawURL: rawurl,
+			Host:   host,
 			Owner:  ss[0],
 		}, nil
 	}
 	repo := Repo{
 		RawURL: rawurl,
+		Host:   host,
 		Owner:  path.Join(ss[:len(ss)-1]...),
 		Name:   ss[len(ss)-1],
 	}
 	return repo, nil
 }
 
-func getInfo(ctx *context.Context) (context.GitInfo, error) {
-	if !isRepo(ctx) {
-		return context.GitInfo{}, errors.New("current folder is not a git repository")
+func getInfo(ctx *context.Context, repo Repository) (context.GitInfo, error) {
+	if !repo.IsRepo() {
+		return context.GitInfo{}, contextError(ctx, errors.New("current folder is not a git repository"), "git rev-parse")
+	}
+	if err := checkShallow(ctx, repo); err != nil {
+		return context.GitInfo{}, err
 	}
-	info, err := getGitInfo(ctx)
+	info, err := getGitInfo(ctx, repo)
 
 	return info, err
 }
 
-func getGitInfo(ctx *context.Context) (context.GitInfo, error) {
-	full, err := getFullCommit(ctx)
+// errUnshallowUnsupported is returned by backends that can't fetch the
+// history of shallow clones.
+var errUnshallowUnsupported = errors.New("the go-git backend can't fetch the history of shallow clones")
+
+// Shallow clone modes.
+const (
+	shallowFetch  = "fetch"
+	shallowFail   = "fail"
+	shallowIgnore = "ignore"
+)
+
+// checkShallow fetches the whole history and the tags of shallow clones, like
+// the ones of actions/checkout with its default fetch-depth of 1, as the tags
+// and the first commit can't be found without them.
+func checkShallow(ctx *context.Context, repo Repository) error {
+	mode := ctx.Config.Git.Shallow
+	switch mode {
+	case "", shallowFetch, shallowFail:
+	case shallowIgnore:
+		return nil
+	default:
+		return fmt.Errorf("invalid git.shallow: %q", mode)
+	}
+
+	shallow, err := repo.IsShallow()
+	if err != nil {
+		return contextError(ctx, fmt.Errorf("couldn't check for a shallow clone: %w", err), "git rev-parse")
+	}
+	if !shallow {
+		return nil
+	}
+	if mode == shallowFail {
+		return errors.New("shallow clone detected, the history and tags are incomplete: set fetch-depth: 0 on actions/checkout, or git.shallow: fetch to fetch them")
+	}
+
+	fmt.Println("shallow clone detected, fetching the whole history and the tags...")
+	if err := repo.Unshallow(); errors.Is(err, errUnshallowUnsupported) {
+		fmt.Printf("%s, the changelog may be incomplete: set fetch-depth: 0 on actions/checkout\n", err)
+		return nil
+	} else if err != nil {
+		return contextError(ctx, fmt.Errorf("couldn't fetch the history of the shallow clone, set fetch-depth: 0 on actions/checkout: %w", err), "git fetch")
+	}
+	return nil
+}
+
+func getGitInfo(ctx *context.Context, repo Repository) (context.GitInfo, error) {
+	full, err := repo.Resolve("HEAD")
 	if err != nil {
 		return context.GitInfo{}, fmt.Errorf("couldn't get current commit: %w", err)
 	}
-	first, err := getFirstCommit(ctx)
+	first, err := repo.FirstCommit()
 	if err != nil {
 		return context.GitInfo{}, fmt.Errorf("couldn't get first commit: %w", err)
 	}
 
-	gitURL, err := getURL(ctx)
+	gitURL, err := repo.RemoteURL()
 	if err != nil {
 		return context.GitInfo{}, fmt.Errorf("couldn't get remote URL: %w", err)
 	}
@@ -107,28 +171,34 @@ func getGitInfo(ctx *context.Context) (context.GitInfo, error) {
 	}
 
 	var excluding []string
-	tag, err := getTag(ctx, excluding)
+	tag, err := getTag(repo, excluding)
 	if err != nil {
 		return context.GitInfo{
 			Commit:      full,
 			FirstCommit: first,
 			CurrentTag:  "v0.0.0",
-		}, errors.New("git doesn't contain any tags")
+		}, contextError(ctx, errors.New("git doesn't contain any tags"), "git tag lookup")
 	}
 
-	previous, _ := getPreviousTag(ctx, tag, excluding)
+	previous, _ := getPreviousTag(repo, tag, excluding)
+
+	date, err := repo.TagDate(tag)
+	if err != nil {
+		return context.GitInfo{}, fmt.Errorf("couldn't get date of tag %s: %w", tag, err)
+	}
 
 	return context.GitInfo{
 		CurrentTag:  tag,
 		PreviousTag: previous,
 		Commit:      full,
 		FirstCommit: first,
+		TagDate:     date,
 	}, nil
 }
 
-func validate(ctx *context.Context) error {
-	_, err := clean(Exec(ctx, "describe", "--exact-match", "--tags", "--match", ctx.Git.CurrentTag))
-	if err != nil {
+func validate(ctx *context.Context, repo Repository) error {
+	tags, err := repo.TagsPointingAt("HEAD")
+	if err != nil || !slices.Contains(tags, ctx.Git.CurrentTag) {
 		return errWrongRef{
 			commit: ctx.Git.Commit,
 			tag:    ctx.Git.CurrentTag,
@@ -137,21 +207,13 @@ func validate(ctx *context.Context) error {
 	return nil
 }
 
-func getFullCommit(ctx *context.Context) (string, error) {
-	return clean(Exec(ctx, "show", "--format=%H", "HEAD", "--quiet"))
-}
-
-func getFirstCommit(ctx *context.Context) (string, error) {
-	return clean(Exec(ctx, "rev-list", "--max-parents=0", "HEAD"))
-}
-
-func getTag(ctx *context.Context, excluding []string) (string, error) {
+func getTag(repo Repository, excluding []string) (string, error) {
 	for _, fn := range []func() ([]string, error){
 		func() ([]string, error) {
-			return gitTagsPointingAt(ctx, "HEAD")
+			return repo.TagsPointingAt("HEAD")
 		},
 		func() ([]string, error) {
-			return cleanAllLines(gitDescribe(ctx, "HEAD", excluding))
+			return cleanAllLines(repo.Describe("HEAD", excluding))
 		},
 	} {
 		tags, err := fn()
@@ -166,14 +228,14 @@ func getTag(ctx *context.Context, excluding []string) (string, error) {
 	return "", nil
 }
 
-func getPreviousTag(ctx *context.Context, current string, excluding []string) (string, error) {
+func getPreviousTag(repo Repository, current string, excluding []string) (string, error) {
 	for _, fn := range []func() ([]string, error){
 		func() ([]string, error) {
-			sha, err := previousTagSha(ctx, current, excluding)
+			sha, err := previousTagSha(repo, current, excluding)
 			if err != nil {
 				return nil, err
 			}
-			return gitTagsPointingAt(ctx, sha)
+			return repo.TagsPointingAt(sha)
 		},
 	} {
 		tags, err := fn()
@@ -188,42 +250,12 @@ func getPreviousTag(ctx *context.Context, current string, excluding []string) (s
 	return "", nil
 }
 
-func gitTagsPointingAt(ctx *context.Context, ref string) ([]string, error) {
-	var args []string
-	args = append(
-		args,
-		"tag",
-		"--points-at",
-		ref,
-		"--sort",
-		"-version:refname",
-	)
-	return cleanAllLines(Exec(ctx, args...))
-}
-
-func gitDescribe(ctx *context.Context, ref string, excluding []string) (string, error) {
-	args := []string{
-		"describe",
-		"--tags",
-		"--abbrev=0",
-		ref,
-	}
-	for _, exclude := range excluding {
-		args = append(args, "--exclude="+exclude)
-	}
-	return clean(Exec(ctx, args...))
-}
-
-func previousTagSha(ctx *context.Context, current string, excluding []string) (string, error) {
-	tag, err := gitDescribe(ctx, fmt.Sprintf("tags/%s^", current), excluding)
+func previousTagSha(repo Repository, current string, excluding []string) (string, error) {
+	tag, err := repo.Describe(fmt.Sprintf("tags/%s^", current), excluding)
 	if err != nil {
 		return "", err
 	}
-	return clean(Exec(ctx, "rev-list", "-n1", tag))
-}
-
-func getURL(ctx *context.Context) (string, error) {
-	return clean(Exec(ctx, "ls-remote", "--get-url"))
+	return repo.Resolve(tag)
 }
 
 func filterOut(tags []string, exclude []string) string {
@@ -253,14 +285,13 @@ func (r Repo) isSCM() bool {
 	return r.Owner != "" && r.Name != ""
 }
 
-// isRepo returns true if current folder is a git repository.
-func isRepo(ctx *context.Context) bool {
-	out, err := Exec(ctx, "rev-parse", "--is-inside-work-tree")
-	return err == nil && strings.TrimSpace(out) == "true"
+// CLI runs the git binary in Dir, or in the current directory when empty.
+type CLI struct {
+	Dir string
 }
 
-// runWithEnv runs a git command and returns its output or errors.
-func runWithEnv(ctx *context.Context, env []string, args ...string) (string, error) {
+// Run implements context.GitRunner.
+func (c CLI) Run(ctx stdctx.Context, env []string, stdin io.Reader, args ...string) (string, error) {
 	extraArgs := []string{
 		"-c", "log.showSignature=false",
 	}
@@ -271,6 +302,8 @@ func runWithEnv(ctx *context.Context, env []string, args ...string) (string, err
 	stdout := bytes.Buffer{}
 	stderr := bytes.Buffer{}
 
+	cmd.Dir = c.Dir
+	cmd.Stdin = stdin
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	cmd.Env = append(cmd.Env, env...)
@@ -284,9 +317,40 @@ func runWithEnv(ctx *context.Context, env []string, args ...string) (string, err
 	return stdout.String(), nil
 }
 
+// runWithEnv runs a git command and returns its output or errors.
+func runWithEnv(ctx *context.Context, env []string, stdin io.Reader, args ...string) (string, error) {
+	var runner context.GitRunner = CLI{Dir: ctx.Dir}
+	if ctx.GitRunner != nil {
+		runner = ctx.GitRunner
+	}
+	out, err := runner.Run(ctx, env, stdin, args...)
+	if err != nil {
+		return "", contextError(ctx, err, "git "+args[0])
+	}
+	return out, nil
+}
+
 // Exec runs a git command and returns its output or errors.
 func Exec(ctx *context.Context, args ...string) (string, error) {
-	return runWithEnv(ctx, []string{}, args...)
+	return runWithEnv(ctx, []string{}, nil, args...)
+}
+
+// ExecWithInput runs a git command reading input from stdin and returns its output or errors.
+func ExecWithInput(ctx *context.Context, input string, args ...string) (string, error) {
+	return runWithEnv(ctx, []string{}, strings.NewReader(input), args...)
+}
+
+// contextError replaces err with a clear error when it was caused by the
+// context timing out or being cancelled during the given step.
+func contextError(ctx *context.Context, err error, during string) error {
+	switch {
+	case errors.Is(ctx.Err(), stdctx.DeadlineExceeded):
+		return fmt.Errorf("timed out during %s: %w", during, ctx.Err())
+	case errors.Is(ctx.Err(), stdctx.Canceled):
+		return fmt.Errorf("cancelled during %s: %w", during, ctx.Err())
+	default:
+		return err
+	}
 }
 
 // clean the output.
@@ -3,8 +3,9 @@ package git
 import (
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
-	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,8 +15,14 @@ import (
 	"golang.org/x/oauth2"
 )
 
+const (
+	defaultHost   = "github.com"
+	defaultAPIURL = "https://api.github.com"
+)
+
 // Repo represents a repository
 type Repo struct {
+	Host   string
 	Owner  string
 	Name   string
 	RawURL string
@@ -30,12 +37,27 @@ func (r Repo) String() string {
 
 // Client interface
 type Client interface {
-	Changelog(ctx *context.Context, repo Repo, prev, current string) (string, error)
+	Changelog(ctx *context.Context, repo Repo, prev, current string) ([]Commit, error)
 }
 
 // NewClient creates a new client depending on the token type
 func NewClient(ctx *context.Context) (Client, error) {
-	return newGitHub(ctx, ctx.Token)
+	ts, err := tokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newGitHub(ctx, ts)
+}
+
+// tokenSource returns the GitHub App installation token source when an app is
+// configured, the static token otherwise.
+func tokenSource(ctx *context.Context) (oauth2.TokenSource, error) {
+	if ctx.GitHubApp.ID != 0 {
+		return newAppTokenSource(ctx, ctx.GitHubApp)
+	}
+	return oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: ctx.Token},
+	), nil
 }
 
 type githubClient struct {
@@ -43,62 +65,125 @@ type githubClient struct {
 }
 
 // newGitHub returns a github client implementation.
-func newGitHub(ctx *context.Context, token string) (*githubClient, error) {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
+func newGitHub(ctx *context.Context, ts oauth2.TokenSource) (*githubClient, error) {
+	httpClient, err := newHTTPClient(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
 
-	httpClient := oauth2.NewClient(ctx, ts)
-	base := httpClient.Transport.(*oauth2.Transport).Base
-	if base == nil || reflect.ValueOf(base).IsNil() {
-		base = http.DefaultTransport
+	client, err := withEnterpriseURLs(ctx, github.NewClient(httpClient))
+	if err != nil {
+		return nil, err
 	}
 
-	base.(*http.Transport).TLSClientConfig = &tls.Config{}
-	base.(*http.Transport).Proxy = http.ProxyFromEnvironment
-	httpClient.Transport.(*oauth2.Transport).Base = base
+	return &githubClient{client: client}, nil
+}
 
-	client := github.NewClient(httpClient)
+// newHTTPClient returns the HTTP client of the GitHub API requests,
+// authenticated by the token source.
+func newHTTPClient(ctx *context.Context, ts oauth2.TokenSource) (*http.Client, error) {
+	transport, err := newTransport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := oauth2.NewClient(ctx, ts)
+	httpClient.Transport.(*oauth2.Transport).Base = transport
+	return httpClient, nil
+}
 
-	return &githubClient{client: client}, nil
+// newTransport returns the base transport of the GitHub API requests.
+func newTransport(ctx *context.Context) (http.RoundTripper, error) {
+	maxWait := defaultMaxWait
+	if s := ctx.Config.RateLimit.MaxWait; s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate_limit.max_wait: %w", err)
+		}
+		maxWait = d
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: ctx.Config.GitHubURLs.SkipTLSVerify, //nolint:gosec
+	}
+	base.Proxy = http.ProxyFromEnvironment
+
+	transport := http.RoundTripper(newRateLimitTransport(base, maxWait))
+	if dir := ctx.Config.Cache.Dir; dir != "" {
+		transport = newCacheTransport(transport, dir)
+	}
+	return transport, nil
 }
 
-func (c *githubClient) checkRateLimit(ctx *context.Context) {
-	limits, _, err := c.client.RateLimit.Get(ctx)
+// withEnterpriseURLs points the client to the GitHub Enterprise Server instance, if any.
+func withEnterpriseURLs(ctx *context.Context, client *github.Client) (*github.Client, error) {
+	api, upload := enterpriseURLs(ctx)
+	if api == "" {
+		return client, nil
+	}
+	client, err := client.WithEnterpriseURLs(api, upload)
 	if err != nil {
-		fmt.Println("could not check rate limits, hoping for the best...")
-		return
+		return nil, fmt.Errorf("invalid GitHub Enterprise URLs: %w", err)
 	}
-	if limits.Core.Remaining > 100 { // 100 should be safe enough
-		return
+	return client, nil
+}
+
+// lookupHost resolves host names, replaced in tests.
+var lookupHost = net.DefaultResolver.LookupHost
+
+// enterpriseURLs returns the API and upload URLs of the GitHub Enterprise
+// Server instance, or empty strings for github.com. They are taken from the
+// github_urls config, the GITHUB_API_URL and GITHUB_SERVER_URL variables set
+// by GitHub Actions, or the host of the remote URL, in that order. Remote
+// hosts that don't resolve, like SSH host aliases, are not taken for one.
+func enterpriseURLs(ctx *context.Context) (api, upload string) {
+	if api := ctx.Config.GitHubURLs.API; api != "" {
+		upload := ctx.Config.GitHubURLs.Upload
+		if upload == "" {
+			upload = strings.TrimSuffix(strings.TrimSuffix(api, "/"), "/api/v3")
+		}
+		return api, upload
+	}
+
+	if api := ctx.Env["GITHUB_API_URL"]; api != "" {
+		if api == defaultAPIURL {
+			return "", ""
+		}
+		upload := ctx.Env["GITHUB_SERVER_URL"]
+		if upload == "" {
+			upload = strings.TrimSuffix(strings.TrimSuffix(api, "/"), "/api/v3")
+		}
+		return api, upload
+	}
+
+	repo, err := ExtractRepoFromConfig(ctx)
+	if err != nil || repo.Host == "" || repo.Host == defaultHost {
+		return "", ""
 	}
-	sleep := limits.Core.Reset.UTC().Sub(time.Now().UTC())
-	if sleep <= 0 {
-		sleep = 15 * time.Second
+	if _, err := lookupHost(ctx, repo.Host); err != nil {
+		fmt.Printf("remote host %s doesn't resolve, using github.com, set github_urls for GitHub Enterprise Server\n", repo.Host)
+		return "", ""
 	}
-	fmt.Printf("token too close to rate limiting, will sleep for %s before continuing...", sleep)
-	time.Sleep(sleep)
-	c.checkRateLimit(ctx)
+	server := "https://" + repo.Host
+	return server, server
 }
 
 // Changelog returns a changelog for the given repository
-func (c *githubClient) Changelog(ctx *context.Context, repo Repo, prev, current string) (string, error) {
-	c.checkRateLimit(ctx)
-	var log []string
+func (c *githubClient) Changelog(ctx *context.Context, repo Repo, prev, current string) ([]Commit, error) {
+	var log []Commit
+	var total int
+	var mergeBase string
 	opts := &github.ListOptions{PerPage: 100}
 
 	for {
 		result, resp, err := c.client.Repositories.CompareCommits(ctx, repo.Owner, repo.Name, prev, current, opts)
 		if err != nil {
-			return "", err
+			return nil, contextError(ctx, err, "GitHub compare of "+prev+"..."+current)
 		}
+		total = result.GetTotalCommits()
+		mergeBase = result.GetMergeBaseCommit().GetSHA()
 		for _, commit := range result.Commits {
-			log = append(log, fmt.Sprintf(
-				"%s: %s (@%s)",
-				commit.GetSHA(),
-				strings.Split(commit.Commit.GetMessage(), "\n")[0],
-				commit.GetAuthor().GetLogin(),
-			))
+			log = append(log, newGitHubCommit(commit))
 		}
 		if resp.NextPage == 0 {
 			break
@@ -106,5 +191,70 @@ func (c *githubClient) Changelog(ctx *context.Context, repo Repo, prev, current
 		opts.Page = resp.NextPage
 	}
 
-	return strings.Join(log, "\n"), nil
+	if len(log) >= total {
+		return log, nil
+	}
+
+	// The compare endpoint caps the number of commits it returns, list the
+	// history of the current ref up to the merge base instead.
+	fmt.Printf("compare returned %d of %d commits, listing the history of %s instead...\n", len(log), total, current)
+	history, err := c.history(ctx, repo, current, mergeBase)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) != total {
+		fmt.Printf("listed %d of %d commits between %s and %s, the changelog may be incomplete\n", len(history), total, prev, current)
+	}
+	return history, nil
+}
+
+// history lists the commits reachable from ref and not from the stop commit,
+// newest first, and returns them oldest first like the compare endpoint does.
+func (c *githubClient) history(ctx *context.Context, repo Repo, ref, stop string) ([]Commit, error) {
+	var log []Commit
+	walk := newHistoryWalk(stop)
+	opts := &github.CommitsListOptions{
+		SHA:         ref,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	for page := 1; ; page++ {
+		commits, resp, err := c.client.Repositories.ListCommits(ctx, repo.Owner, repo.Name, opts)
+		if err != nil {
+			return nil, contextError(ctx, err, "GitHub history listing of "+ref)
+		}
+		for _, commit := range commits {
+			var parents []string
+			for _, p := range commit.Parents {
+				parents = append(parents, p.GetSHA())
+			}
+			if walk.add(commit.GetSHA(), parents) {
+				log = append(log, newGitHubCommit(commit))
+			}
+			if walk.done() {
+				break
+			}
+		}
+		if walk.done() || resp.NextPage == 0 {
+			break
+		}
+		if page == maxHistoryPages {
+			fmt.Printf("merge base %s not reached within %d pages of the history of %s\n", stop, maxHistoryPages, ref)
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	slices.Reverse(log)
+	return log, nil
+}
+
+// newGitHubCommit builds a Commit from a GitHub API commit.
+func newGitHubCommit(commit *github.RepositoryCommit) Commit {
+	c := newCommit(commit.GetSHA(), commit.Commit.GetMessage())
+	c.Login = commit.GetAuthor().GetLogin()
+	c.AuthorName = commit.Commit.GetAuthor().GetName()
+	c.AuthorEmail = commit.Commit.GetAuthor().GetEmail()
+	c.Date = commit.Commit.GetCommitter().GetDate().Time
+	return c
 }
@@ -0,0 +1,164 @@
+package git
+
+import (
+	stdctx "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/config"
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+func TestGitHubChangelogTruncated(t *testing.T) {
+	sha := func(c string) string { return strings.Repeat(c, 40) }
+	commit := func(c, msg string, parents ...string) map[string]any {
+		var ps []map[string]any
+		for _, p := range parents {
+			ps = append(ps, map[string]any{"sha": sha(p)})
+		}
+		return map[string]any{
+			"sha":     sha(c),
+			"commit":  map[string]any{"message": msg},
+			"parents": ps,
+		}
+	}
+
+	var listed bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/acme/app/compare/v1.0.0...v1.1.0", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"total_commits":     3,
+			"merge_base_commit": map[string]any{"sha": sha("1")},
+			"commits":           []any{commit("5", "Merge pull request #5 from acme/search", "3", "b")},
+		})
+	})
+	mux.HandleFunc("/api/v3/repos/acme/app/commits", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sha") != "v1.1.0" || r.URL.Query().Get("page") != "" {
+			t.Errorf("unexpected history request %s", r.URL)
+		}
+		listed = true
+		w.Header().Set("Link", `<`+"http://"+r.Host+r.URL.Path+`?page=2>; rel="next"`)
+		// the branch commit is dated before the merge base, but merged after it.
+		_ = json.NewEncoder(w).Encode([]any{
+			commit("5", "Merge pull request #5 from acme/search", "3", "b"),
+			commit("3", "fix: typo", "1"),
+			commit("1", "chore: release v1.0.0", "0"),
+			commit("b", "feat: search", "0"),
+			commit("0", "chore: initial"),
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var cfg config.Config
+	cfg.GitHubURLs.API = srv.URL + "/api/v3/"
+	ctx := context.New(cfg)
+	client, err := NewClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := client.Changelog(ctx, Repo{Owner: "acme", Name: "app"}, "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !listed {
+		t.Fatal("the history wasn't listed")
+	}
+	var subjects []string
+	for _, c := range commits {
+		subjects = append(subjects, c.Subject)
+	}
+	if want := []string{"feat: search", "fix: typo", "Merge pull request #5 from acme/search"}; !slices.Equal(subjects, want) {
+		t.Fatalf("commits = %q, want %q", subjects, want)
+	}
+}
+
+// fakeRunner answers the git commands with the recorded outputs.
+type fakeRunner map[string]string
+
+func (f fakeRunner) Run(_ stdctx.Context, _ []string, _ io.Reader, args ...string) (string, error) {
+	out, ok := f[strings.Join(args, " ")]
+	if !ok {
+		return "", fmt.Errorf("unexpected git %s", strings.Join(args, " "))
+	}
+	return out, nil
+}
+
+func TestEnterpriseURLs(t *testing.T) {
+	lookup := lookupHost
+	t.Cleanup(func() { lookupHost = lookup })
+	lookupHost = func(_ stdctx.Context, host string) ([]string, error) {
+		if host == "github.example.com" {
+			return []string{"10.0.0.1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	for name, tt := range map[string]struct {
+		api, upload string
+		env         map[string]string
+		remote      string
+		wantAPI     string
+		wantUpload  string
+	}{
+		"config": {
+			api:        "https://ghes.example.com/api/v3/",
+			env:        map[string]string{"GITHUB_API_URL": "https://other.example.com/api/v3"},
+			remote:     "git@github.example.com:acme/app.git",
+			wantAPI:    "https://ghes.example.com/api/v3/",
+			wantUpload: "https://ghes.example.com",
+		},
+		"config upload": {
+			api:        "https://ghes.example.com/api/v3/",
+			upload:     "https://ghes.example.com/api/uploads/",
+			wantAPI:    "https://ghes.example.com/api/v3/",
+			wantUpload: "https://ghes.example.com/api/uploads/",
+		},
+		"env": {
+			env:        map[string]string{"GITHUB_API_URL": "https://ghes.example.com/api/v3", "GITHUB_SERVER_URL": "https://ghes.example.com"},
+			remote:     "git@github.example.com:acme/app.git",
+			wantAPI:    "https://ghes.example.com/api/v3",
+			wantUpload: "https://ghes.example.com",
+		},
+		"env github.com": {
+			env:    map[string]string{"GITHUB_API_URL": "https://api.github.com"},
+			remote: "git@github.example.com:acme/app.git",
+		},
+		"remote": {
+			remote:     "git@github.example.com:acme/app.git",
+			wantAPI:    "https://github.example.com",
+			wantUpload: "https://github.example.com",
+		},
+		"remote github.com": {
+			remote: "https://github.com/acme/app.git",
+		},
+		"ssh host alias": {
+			remote: "git@work-gh:acme/app.git",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.GitHubURLs.API = tt.api
+			cfg.GitHubURLs.Upload = tt.upload
+			ctx := context.New(cfg)
+			ctx.Env = tt.env
+			ctx.GitRunner = fakeRunner{
+				"rev-parse --is-inside-work-tree": "true",
+				"ls-remote --get-url":             tt.remote,
+			}
+
+			api, upload := enterpriseURLs(ctx)
+			if api != tt.wantAPI || upload != tt.wantUpload {
+				t.Errorf("got %q %q, want %q %q", api, upload, tt.wantAPI, tt.wantUpload)
+			}
+		})
+	}
+}
@@ -0,0 +1,461 @@
+package git
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// goGitRepository reads the repository with go-git, so git doesn't need to
+// be installed.
+type goGitRepository struct {
+	ctx  *context.Context
+	repo *gogit.Repository
+	// shallow holds the commits of a shallow clone whose parents are missing.
+	shallow map[plumbing.Hash]bool
+}
+
+func openGoGitRepository(ctx *context.Context) (Repository, error) {
+	dir := ctx.Dir
+	if dir == "" {
+		dir = "."
+	}
+	repo, err := gogit.PlainOpenWithOptions(dir, &gogit.PlainOpenOptions{DetectDotGit: true})
+	if errors.Is(err, gogit.ErrRepositoryNotExists) {
+		return goGitRepository{ctx: ctx}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	shallows, err := repo.Storer.Shallow()
+	if err != nil {
+		return nil, err
+	}
+	shallow := map[plumbing.Hash]bool{}
+	for _, h := range shallows {
+		shallow[h] = true
+	}
+	return goGitRepository{ctx: ctx, repo: repo, shallow: shallow}, nil
+}
+
+func (r goGitRepository) IsRepo() bool {
+	if r.repo == nil {
+		return false
+	}
+	_, err := r.repo.Worktree()
+	return err == nil
+}
+
+func (r goGitRepository) IsShallow() (bool, error) {
+	return len(r.shallow) > 0, nil
+}
+
+// parents returns the parents of the commit, none at the boundary of a
+// shallow clone like git.
+func (r goGitRepository) parents(c *object.Commit) []plumbing.Hash {
+	if r.shallow[c.Hash] {
+		return nil
+	}
+	return c.ParentHashes
+}
+
+func (r goGitRepository) Unshallow() error {
+	return errUnshallowUnsupported
+}
+
+func (r goGitRepository) RemoteURL() (string, error) {
+	remote, err := r.repo.Remote(gogit.DefaultRemoteName)
+	if err != nil {
+		return "", err
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", fmt.Errorf("remote %s has no URL", gogit.DefaultRemoteName)
+	}
+	return urls[0], nil
+}
+
+func (r goGitRepository) Resolve(rev string) (string, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", rev, err)
+	}
+	return hash.String(), nil
+}
+
+func (r goGitRepository) FirstCommit() (string, error) {
+	head, err := r.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	var first string
+	err = r.walk([]plumbing.Hash{head.Hash()}, nil, func(c *object.Commit) {
+		if first == "" && len(r.parents(c)) == 0 {
+			first = c.Hash.String()
+		}
+	})
+	return first, err
+}
+
+func (r goGitRepository) TagsPointingAt(rev string) ([]string, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rev, err)
+	}
+	tags, err := r.tagsByCommit()
+	if err != nil {
+		return nil, err
+	}
+	return tags[*hash], nil
+}
+
+func (r goGitRepository) Describe(rev string, excluding []string) (string, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", rev, err)
+	}
+	tags, err := r.tagsByCommit()
+	if err != nil {
+		return "", err
+	}
+
+	// the closest tag is found by walking the history breadth first.
+	queue := []plumbing.Hash{*hash}
+	seen := map[plumbing.Hash]bool{*hash: true}
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		for _, tag := range tags[h] {
+			if !excluded(tag, excluding) {
+				return tag, nil
+			}
+		}
+		c, err := r.repo.CommitObject(h)
+		if err != nil {
+			return "", err
+		}
+		for _, p := range r.parents(c) {
+			if !seen[p] {
+				seen[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	return "", errors.New("no names found, cannot describe anything")
+}
+
+func (r goGitRepository) TagDate(tag string) (time.Time, error) {
+	ref, err := r.repo.Tag(tag)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", tag, err)
+	}
+	if t, err := r.repo.TagObject(ref.Hash()); err == nil {
+		return t.Tagger.When, nil
+	}
+	c, err := r.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Committer.When, nil
+}
+
+func (r goGitRepository) Log(revs ...string) ([]Commit, error) {
+	var include, exclude []plumbing.Hash
+	for _, rev := range revs {
+		from, to, isRange := strings.Cut(rev, "..")
+		if isRange {
+			hash, err := r.repo.ResolveRevision(plumbing.Revision(from))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", from, err)
+			}
+			exclude = append(exclude, *hash)
+			rev = to
+		}
+		hash, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rev, err)
+		}
+		include = append(include, *hash)
+	}
+
+	excluded := map[plumbing.Hash]bool{}
+	if err := r.walk(exclude, nil, func(c *object.Commit) {
+		excluded[c.Hash] = true
+	}); err != nil {
+		return nil, err
+	}
+
+	var objects []*object.Commit
+	if err := r.walk(include, excluded, func(c *object.Commit) {
+		objects = append(objects, c)
+	}); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].Committer.When.After(objects[j].Committer.When)
+	})
+
+	abbrev, err := r.abbreviator()
+	if err != nil {
+		return nil, err
+	}
+	commits := make([]Commit, 0, len(objects))
+	for _, c := range objects {
+		commit := newCommit(abbrev(c.Hash), c.Message)
+		commit.Date = c.Committer.When
+		commit.AuthorName = c.Author.Name
+		commit.AuthorEmail = c.Author.Email
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// minAbbrev is the shortest abbreviation of the object names, like git.
+const minAbbrev = 7
+
+// abbreviator returns a function abbreviating the object names like git's
+// %h: to a length growing with the number of objects, and long enough to be
+// unique in the repository.
+func (r goGitRepository) abbreviator() (func(plumbing.Hash) string, error) {
+	lister, ok := r.repo.Storer.(interface {
+		HashesWithPrefix(prefix []byte) ([]plumbing.Hash, error)
+	})
+	if !ok {
+		return func(h plumbing.Hash) string { return h.String()[:minAbbrev] }, nil
+	}
+	hashes, err := lister.HashesWithPrefix(nil)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		names = append(names, h.String())
+	}
+	sort.Strings(names)
+	names = slices.Compact(names)
+	return func(h plumbing.Hash) string {
+		return abbrevName(names, h.String())
+	}, nil
+}
+
+// abbrevName abbreviates the object name to the length git picks for the
+// sorted names of the objects of the repository.
+func abbrevName(names []string, name string) string {
+	// like git, keep twice the bits needed to count the objects, 4 a
+	// character, so that new objects seldom collide with the abbreviations.
+	n := max(minAbbrev, (bits.Len(uint(len(names)))+1)/2)
+	i := sort.SearchStrings(names, name)
+	neighbours := []int{i - 1, i}
+	if i < len(names) && names[i] == name {
+		neighbours = []int{i - 1, i + 1}
+	}
+	for _, j := range neighbours {
+		if j >= 0 && j < len(names) {
+			n = max(n, commonPrefix(names[j], name)+1)
+		}
+	}
+	return name[:min(n, len(name))]
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// PatchIDs hashes the changed lines of every file, ignoring whitespace and
+// line numbers like git patch-id --stable. Merge commits are left out.
+func (r goGitRepository) PatchIDs(shas ...string) (map[string]string, error) {
+	ids := map[string]string{}
+	for _, sha := range shas {
+		hash, err := r.repo.ResolveRevision(plumbing.Revision(sha))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sha, err)
+		}
+		c, err := r.repo.CommitObject(*hash)
+		if err != nil {
+			return nil, err
+		}
+		if len(r.parents(c)) > 1 {
+			continue
+		}
+		id, err := r.patchID(c)
+		if err != nil {
+			return nil, err
+		}
+		if id != "" {
+			ids[c.Hash.String()] = id
+		}
+	}
+	return ids, nil
+}
+
+func (r goGitRepository) patchID(c *object.Commit) (string, error) {
+	var from *object.Tree
+	if parents := r.parents(c); len(parents) == 1 {
+		parent, err := r.repo.CommitObject(parents[0])
+		if err != nil {
+			return "", err
+		}
+		if from, err = parent.Tree(); err != nil {
+			return "", err
+		}
+	}
+	to, err := c.Tree()
+	if err != nil {
+		return "", err
+	}
+	changes, err := object.DiffTreeWithOptions(r.ctx, from, to, nil)
+	if err != nil {
+		return "", err
+	}
+	patch, err := changes.PatchContext(r.ctx)
+	if err != nil {
+		return "", err
+	}
+
+	files := patch.FilePatches()
+	if len(files) == 0 {
+		return "", nil
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return filePatchPath(files[i]) < filePatchPath(files[j])
+	})
+	h := sha1.New() //nolint:gosec
+	for _, file := range files {
+		fmt.Fprintln(h, filePatchPath(file))
+		for _, chunk := range file.Chunks() {
+			var sign string
+			switch chunk.Type() {
+			case fdiff.Add:
+				sign = "+"
+			case fdiff.Delete:
+				sign = "-"
+			default:
+				continue
+			}
+			for _, line := range strings.Split(chunk.Content(), "\n") {
+				fmt.Fprintln(h, sign+strings.Join(strings.Fields(line), ""))
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func filePatchPath(p fdiff.FilePatch) string {
+	from, to := p.Files()
+	if to != nil {
+		return to.Path()
+	}
+	return from.Path()
+}
+
+// walk calls fn for every commit reachable from the given commits, skipping
+// the stop commits and their history.
+func (r goGitRepository) walk(from []plumbing.Hash, stop map[plumbing.Hash]bool, fn func(*object.Commit)) error {
+	seen := map[plumbing.Hash]bool{}
+	queue := append([]plumbing.Hash(nil), from...)
+	for len(queue) > 0 {
+		if err := r.ctx.Err(); err != nil {
+			return contextError(r.ctx, err, "git history walk")
+		}
+		h := queue[0]
+		queue = queue[1:]
+		if seen[h] || stop[h] {
+			continue
+		}
+		seen[h] = true
+		c, err := r.repo.CommitObject(h)
+		if err != nil {
+			return err
+		}
+		fn(c)
+		queue = append(queue, r.parents(c)...)
+	}
+	return nil
+}
+
+// tagsByCommit returns the tags of every tagged commit, highest version first.
+func (r goGitRepository) tagsByCommit() (map[plumbing.Hash][]string, error) {
+	refs, err := r.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	tags := map[plumbing.Hash][]string{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		hash := ref.Hash()
+		if t, err := r.repo.TagObject(hash); err == nil {
+			c, err := t.Commit()
+			if err != nil {
+				return nil // tags of trees and blobs can't be described.
+			}
+			hash = c.Hash
+		}
+		tags[hash] = append(tags[hash], ref.Name().Short())
+		return nil
+	})
+	for _, names := range tags {
+		sort.Slice(names, func(i, j int) bool {
+			return versionLess(names[j], names[i])
+		})
+	}
+	return tags, err
+}
+
+// excluded reports whether the tag matches one of the glob patterns.
+func excluded(tag string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// versionLess compares the tags like git's version:refname sort, comparing
+// the runs of digits numerically.
+func versionLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,34 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAbbrevName(t *testing.T) {
+	name := "1234567890" + strings.Repeat("0", 30)
+	// 2^16 objects need 9 characters.
+	many := make([]string, 1<<16)
+	for i := range many {
+		many[i] = fmt.Sprintf("%04x", i) + strings.Repeat("0", 36)
+	}
+
+	for desc, tt := range map[string]struct {
+		names []string
+		want  string
+	}{
+		"unique":           {[]string{"0000000000", name, "9999999999"}, "1234567"},
+		"prefix before":    {[]string{"12345678ff", name, "9999999999"}, "123456789"},
+		"prefix after":     {[]string{name, "1234567890ff"}, "12345678900"},
+		"not listed":       {[]string{"12345678ff"}, "123456789"},
+		"many objects":     {many, "123456789"},
+		"full name needed": {[]string{name, name[:39] + "1"}, name},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			if got := abbrevName(tt.names, name); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
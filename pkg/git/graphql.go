@@ -0,0 +1,273 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+// historyQuery lists the history of a revision along with the associated
+// pull requests of every commit, 100 commits at a time. Annotated tags are
+// peeled to the commit they point at.
+const historyQuery = `query($owner: String!, $name: String!, $prev: String!, $current: String!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    prev: object(expression: $prev) {
+      oid
+      ... on Tag { target { oid } }
+    }
+    current: object(expression: $current) {
+      ...history
+      ... on Tag { target { ...history } }
+    }
+  }
+}
+
+fragment history on Commit {
+  history(first: 100, after: $cursor) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      oid
+      parents(first: 10) { nodes { oid } }
+      message
+      committedDate
+      author { name email user { login } }
+      associatedPullRequests(first: 1) {
+        nodes { number labels(first: 20) { nodes { name } } }
+      }
+    }
+  }
+}`
+
+type graphqlCommit struct {
+	OID     string `json:"oid"`
+	Parents struct {
+		Nodes []struct {
+			OID string `json:"oid"`
+		} `json:"nodes"`
+	} `json:"parents"`
+	Message       string    `json:"message"`
+	CommittedDate time.Time `json:"committedDate"`
+	Author        struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+		User  *struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"author"`
+	AssociatedPullRequests struct {
+		Nodes []struct {
+			Number int `json:"number"`
+			Labels struct {
+				Nodes []struct {
+					Name string `json:"name"`
+				} `json:"nodes"`
+			} `json:"labels"`
+		} `json:"nodes"`
+	} `json:"associatedPullRequests"`
+}
+
+// graphqlObject is a commit, or an annotated tag along with its target.
+type graphqlObject struct {
+	OID     string          `json:"oid"`
+	History *graphqlHistory `json:"history"`
+	Target  *graphqlObject  `json:"target"`
+}
+
+// commit returns the commit of the object, peeling annotated tags.
+func (o *graphqlObject) commit() *graphqlObject {
+	if o != nil && o.Target != nil {
+		return o.Target
+	}
+	return o
+}
+
+type graphqlHistory struct {
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Nodes []graphqlCommit `json:"nodes"`
+}
+
+type historyResult struct {
+	Repository *struct {
+		Prev    *graphqlObject `json:"prev"`
+		Current *graphqlObject `json:"current"`
+	} `json:"repository"`
+}
+
+type graphqlClient struct {
+	client *http.Client
+	url    string
+}
+
+// NewGraphQLClient creates a client using the GitHub GraphQL API.
+func NewGraphQLClient(ctx *context.Context) (Client, error) {
+	ts, err := tokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := newHTTPClient(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &graphqlClient{
+		client: httpClient,
+		url:    graphqlURL(ctx),
+	}, nil
+}
+
+// graphqlURL returns the GraphQL endpoint of github.com or of the GitHub
+// Enterprise Server instance.
+func graphqlURL(ctx *context.Context) string {
+	api, _ := enterpriseURLs(ctx)
+	if api == "" {
+		return defaultAPIURL + "/graphql"
+	}
+	api = strings.TrimSuffix(api, "/")
+	if strings.HasSuffix(api, "/api/v3") {
+		return strings.TrimSuffix(api, "/v3") + "/graphql"
+	}
+	return api + "/api/graphql"
+}
+
+// Changelog returns a changelog for the given repository
+func (c *graphqlClient) Changelog(ctx *context.Context, repo Repo, prev, current string) ([]Commit, error) {
+	var log []Commit
+	vars := map[string]any{
+		"owner":   repo.Owner,
+		"name":    repo.Name,
+		"prev":    prev,
+		"current": current,
+	}
+
+	var walk *historyWalk
+	for page := 1; ; page++ {
+		var result historyResult
+		if err := c.query(ctx, historyQuery, vars, &result); err != nil {
+			return nil, contextError(ctx, err, "GitHub GraphQL history query of "+current)
+		}
+		if result.Repository == nil {
+			return nil, fmt.Errorf("repository %s not found", repo)
+		}
+		prevCommit := result.Repository.Prev.commit()
+		if prevCommit == nil {
+			return nil, fmt.Errorf("revision %s not found in %s", prev, repo)
+		}
+		currentCommit := result.Repository.Current.commit()
+		if currentCommit == nil {
+			return nil, fmt.Errorf("revision %s not found in %s", current, repo)
+		}
+		if currentCommit.History == nil {
+			return nil, fmt.Errorf("revision %s of %s is not a commit", current, repo)
+		}
+		if walk == nil {
+			walk = newHistoryWalk(prevCommit.OID)
+		}
+
+		history := currentCommit.History
+		for _, commit := range history.Nodes {
+			if walk.add(commit.OID, commit.parents()) {
+				log = append(log, commit.toCommit())
+			}
+			if walk.done() {
+				break
+			}
+		}
+		if walk.done() || !history.PageInfo.HasNextPage {
+			break
+		}
+		if page == maxHistoryPages {
+			fmt.Printf("%s not reached within %d pages of the history of %s, the changelog may be incomplete\n", prev, maxHistoryPages, current)
+			slices.Reverse(log)
+			return log, nil
+		}
+		vars["cursor"] = history.PageInfo.EndCursor
+	}
+
+	if !walk.reached {
+		fmt.Printf("%s is not an ancestor of %s, the changelog lists its whole history\n", prev, current)
+	}
+	slices.Reverse(log)
+	return log, nil
+}
+
+// parents returns the OIDs of the parents of the commit.
+func (g graphqlCommit) parents() []string {
+	var parents []string
+	for _, p := range g.Parents.Nodes {
+		parents = append(parents, p.OID)
+	}
+	return parents
+}
+
+func (g graphqlCommit) toCommit() Commit {
+	c := newCommit(g.OID, g.Message)
+	c.AuthorName = g.Author.Name
+	c.AuthorEmail = g.Author.Email
+	if g.Author.User != nil {
+		c.Login = g.Author.User.Login
+	}
+	c.Date = g.CommittedDate
+	if prs := g.AssociatedPullRequests.Nodes; len(prs) > 0 {
+		c.PullRequest = prs[0].Number
+		for _, label := range prs[0].Labels.Nodes {
+			c.Labels = append(c.Labels, label.Name)
+		}
+	}
+	return c
+}
+
+// query runs a GraphQL query and decodes its data into out.
+func (c *graphqlClient) query(ctx *context.Context, query string, vars map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{
+		"query":     query,
+		"variables": vars,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("graphql query failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid graphql response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		msgs := make([]string, 0, len(result.Errors))
+		for _, e := range result.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return errors.New("graphql query failed: " + strings.Join(msgs, "; "))
+	}
+	return json.Unmarshal(result.Data, out)
+}
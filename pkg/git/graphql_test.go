@@ -0,0 +1,163 @@
+package git
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/config"
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+// serveFixtures serves the recorded GraphQL responses, picking the fixture by
+// the cursor of the request.
+func serveFixtures(t *testing.T, fixtures map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		cursor, _ := req.Variables["cursor"].(string)
+		name, ok := fixtures[cursor]
+		if !ok {
+			t.Errorf("unexpected cursor %q", cursor)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, err := os.ReadFile(filepath.Join("testdata", "graphql", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGraphQLChangelog(t *testing.T) {
+	srv := serveFixtures(t, map[string]string{
+		"":         "history-1.json",
+		"cursor-1": "history-2.json",
+	})
+	c := &graphqlClient{client: srv.Client(), url: srv.URL}
+
+	commits, err := c.Changelog(context.New(config.Config{}), Repo{Owner: "acme", Name: "app"}, "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var shas []string
+	for _, c := range commits {
+		shas = append(shas, c.SHA[:1])
+	}
+	if want := []string{"2", "3", "4"}; !slices.Equal(shas, want) {
+		t.Fatalf("commits = %v, want %v", shas, want)
+	}
+
+	feat := commits[2]
+	if feat.Subject != "feat: add login (#12)" || feat.Body != "Closes #10" {
+		t.Errorf("message = %q / %q", feat.Subject, feat.Body)
+	}
+	if feat.Login != "jane" || feat.AuthorName != "Jane Doe" || feat.AuthorEmail != "jane@example.com" {
+		t.Errorf("author = %q %q %q", feat.Login, feat.AuthorName, feat.AuthorEmail)
+	}
+	if feat.PullRequest != 12 || !slices.Equal(feat.Labels, []string{"enhancement", "auth"}) {
+		t.Errorf("pull request = %d %v", feat.PullRequest, feat.Labels)
+	}
+	if feat.Date.Day() != 3 {
+		t.Errorf("date = %s", feat.Date)
+	}
+
+	fix := commits[1]
+	if fix.Login != "" || fix.AuthorName != "John Roe" || fix.PullRequest != 0 {
+		t.Errorf("commit without user or pull request = %+v", fix)
+	}
+}
+
+func TestGraphQLChangelogAnnotatedTags(t *testing.T) {
+	// the walk stops at the commit of prev, without requesting the next page.
+	srv := serveFixtures(t, map[string]string{"": "annotated-tags.json"})
+	c := &graphqlClient{client: srv.Client(), url: srv.URL}
+
+	commits, err := c.Changelog(context.New(config.Config{}), Repo{Owner: "acme", Name: "app"}, "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subjects []string
+	for _, c := range commits {
+		subjects = append(subjects, c.Subject)
+	}
+	if want := []string{"feat: add login (#12)", "fix: logout"}; !slices.Equal(subjects, want) {
+		t.Fatalf("commits = %q, want %q", subjects, want)
+	}
+}
+
+func TestGraphQLChangelogMergedBranch(t *testing.T) {
+	// the branch commit is dated before prev but merged after it, the walk
+	// stops once the commits left are ancestors of prev.
+	srv := serveFixtures(t, map[string]string{"": "merged-branch.json"})
+	c := &graphqlClient{client: srv.Client(), url: srv.URL}
+
+	commits, err := c.Changelog(context.New(config.Config{}), Repo{Owner: "acme", Name: "app"}, "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subjects []string
+	for _, c := range commits {
+		subjects = append(subjects, c.Subject)
+	}
+	if want := []string{"feat: search", "fix: typo", "Merge pull request #5 from acme/search"}; !slices.Equal(subjects, want) {
+		t.Fatalf("commits = %q, want %q", subjects, want)
+	}
+}
+
+func TestGraphQLChangelogMaxPages(t *testing.T) {
+	srv := serveFixtures(t, map[string]string{"": "endless.json", "more": "endless.json"})
+	c := &graphqlClient{client: srv.Client(), url: srv.URL}
+
+	commits, err := c.Changelog(context.New(config.Config{}), Repo{Owner: "acme", Name: "app"}, "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != maxHistoryPages {
+		t.Fatalf("%d commits listed, want %d", len(commits), maxHistoryPages)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	srv := serveFixtures(t, map[string]string{"": "errors.json"})
+	c := &graphqlClient{client: srv.Client(), url: srv.URL}
+
+	_, err := c.Changelog(context.New(config.Config{}), Repo{Owner: "acme", Name: "missing"}, "v1.0.0", "v1.1.0")
+	if err == nil || !strings.Contains(err.Error(), "Could not resolve to a Repository") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	for api, want := range map[string]string{
+		"":                                   "https://api.github.com/graphql",
+		"https://github.example.com/api/v3/": "https://github.example.com/api/graphql",
+		"https://github.example.com/api/v3":  "https://github.example.com/api/graphql",
+		"https://github.example.com/":        "https://github.example.com/api/graphql",
+	} {
+		ctx := context.New(config.Config{})
+		ctx.Config.GitHubURLs.API = api
+		ctx.Env = map[string]string{}
+		if got := graphqlURL(ctx); got != want {
+			t.Errorf("graphqlURL(%q) = %q, want %q", api, got, want)
+		}
+	}
+}
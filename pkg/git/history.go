@@ -0,0 +1,56 @@
+package git
+
+import "slices"
+
+// maxHistoryPages bounds the history listed page by page, 100 commits a page,
+// when looking for the commits between two revisions.
+const maxHistoryPages = 50
+
+// historyWalk selects the commits of a history listed newest first that are
+// not reachable from the stop commit, like git log stop..current. It follows
+// the parents of the commits instead of stopping at the stop commit, so the
+// commits of branches merged after it are kept whatever their date.
+type historyWalk struct {
+	stop string
+	// excluded holds the stop commit and the ancestors of it seen so far.
+	excluded map[string]bool
+	// pending holds the parents of the selected commits not seen yet.
+	pending map[string]bool
+	started bool
+	// reached is set once the stop commit is found in the history.
+	reached bool
+}
+
+func newHistoryWalk(stop string) *historyWalk {
+	return &historyWalk{
+		stop:     stop,
+		excluded: map[string]bool{stop: true},
+		pending:  map[string]bool{},
+	}
+}
+
+// add records the next commit of the history and reports whether it is
+// selected.
+func (w *historyWalk) add(sha string, parents []string) bool {
+	w.started = true
+	w.reached = w.reached || sha == w.stop || slices.Contains(parents, w.stop)
+	delete(w.pending, sha)
+	if w.excluded[sha] {
+		for _, p := range parents {
+			w.excluded[p] = true
+			delete(w.pending, p)
+		}
+		return false
+	}
+	for _, p := range parents {
+		if !w.excluded[p] {
+			w.pending[p] = true
+		}
+	}
+	return true
+}
+
+// done reports whether every commit to select has been seen.
+func (w *historyWalk) done() bool {
+	return w.started && len(w.pending) == 0
+}
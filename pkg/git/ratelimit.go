@@ -0,0 +1,152 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxWait is the longest a request waits for a rate limit reset.
+	defaultMaxWait = 10 * time.Minute
+	// maxRetries is the number of retries of server errors.
+	maxRetries = 3
+	// maxRateLimitRetries is the number of retries of rate limited requests.
+	maxRateLimitRetries = 3
+)
+
+// rateLimitTransport waits for the GitHub rate limits to reset instead of
+// failing, and retries server errors with an exponential backoff. Waits are
+// bounded by maxWait and by the deadline of the request context, retries by
+// maxRetries and maxRateLimitRetries.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	maxWait time.Duration
+	backoff time.Duration
+
+	mu        sync.Mutex
+	exhausted bool
+	reset     time.Time
+}
+
+func newRateLimitTransport(base http.RoundTripper, maxWait time.Duration) *rateLimitTransport {
+	return &rateLimitTransport{
+		base:    base,
+		maxWait: maxWait,
+		backoff: time.Second,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if wait := t.untilReset(); wait > 0 {
+		if err := t.sleep(req, wait, "rate limit exhausted"); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		r, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		t.update(resp)
+
+		wait, reason := t.retryAfter(resp, attempt)
+		if reason == "" || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := t.sleep(req, wait, reason); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rewind returns the request to send for the given attempt, with a fresh body
+// for retries.
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+// update records whether the rate limit is exhausted from the X-RateLimit
+// headers of the response.
+func (t *rateLimitTransport) update(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exhausted = remaining == 0
+	t.reset = time.Unix(reset, 0)
+}
+
+// untilReset returns how long to wait before the next request.
+func (t *rateLimitTransport) untilReset() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.exhausted {
+		return 0
+	}
+	return time.Until(t.reset)
+}
+
+// retryAfter returns how long to wait before retrying the request and why, or
+// an empty reason if the response should be returned as is.
+func (t *rateLimitTransport) retryAfter(resp *http.Response, attempt int) (time.Duration, string) {
+	switch {
+	case (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) && attempt < maxRateLimitRetries:
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			// a zero Retry-After still waits, so retries don't hammer the API.
+			return max(time.Duration(s)*time.Second, t.backoff), "secondary rate limit exceeded"
+		}
+		if wait := t.untilReset(); wait > 0 {
+			return wait, "rate limit exceeded"
+		}
+	case resp.StatusCode >= http.StatusInternalServerError && attempt < maxRetries:
+		return t.backoff << attempt, resp.Status
+	}
+	return 0, ""
+}
+
+// sleep waits for d unless it's longer than the max wait or the request
+// context is done first.
+func (t *rateLimitTransport) sleep(req *http.Request, d time.Duration, reason string) error {
+	if d > t.maxWait {
+		return fmt.Errorf("%s: waiting %s exceeds the max wait of %s", reason, d.Round(time.Second), t.maxWait)
+	}
+	if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < d {
+		return fmt.Errorf("%s: waiting %s exceeds the deadline", reason, d.Round(time.Second))
+	}
+	fmt.Printf("%s, waiting %s before retrying...\n", reason, d.Round(time.Second))
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	stdctx "context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitTransportRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "query" {
+			t.Errorf("body = %q", body)
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	transport := newRateLimitTransport(http.DefaultTransport, time.Minute)
+	transport.backoff = time.Millisecond
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("query"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("status = %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestRateLimitTransportMaxWait(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newRateLimitTransport(http.DefaultTransport, time.Minute)}
+
+	_, err := client.Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the max wait of 1m0s") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestRateLimitTransportRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newRateLimitTransport(http.DefaultTransport, time.Hour)}
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Do(req)
+	if err == nil || !strings.Contains(err.Error(), "secondary rate limit exceeded: waiting 1m0s exceeds the deadline") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestRateLimitTransportRetryLimit(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	transport := newRateLimitTransport(http.DefaultTransport, time.Minute)
+	transport.backoff = 10 * time.Millisecond
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != maxRateLimitRetries+1 {
+		t.Fatalf("status = %d after %d calls", resp.StatusCode, calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed < maxRateLimitRetries*transport.backoff {
+		t.Fatalf("retried within %s, zero waits aren't floored", elapsed)
+	}
+}
@@ -0,0 +1,187 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+// Repository backends.
+const (
+	backendCLI   = "cli"
+	backendGoGit = "go-git"
+)
+
+// Repository reads the git repository the changelog is generated for.
+type Repository interface {
+	// IsRepo reports whether the directory is inside a git work tree.
+	IsRepo() bool
+	// IsShallow reports whether the repository is a shallow clone.
+	IsShallow() (bool, error)
+	// Unshallow fetches the whole history and the tags of a shallow clone.
+	Unshallow() error
+	// RemoteURL returns the URL of the default remote.
+	RemoteURL() (string, error)
+	// Resolve returns the full SHA of the commit rev points to.
+	Resolve(rev string) (string, error)
+	// FirstCommit returns the full SHA of the root commit of HEAD.
+	FirstCommit() (string, error)
+	// TagsPointingAt returns the tags pointing at rev, highest version first.
+	TagsPointingAt(rev string) ([]string, error)
+	// Describe returns the closest tag reachable from rev.
+	Describe(rev string, excluding []string) (string, error)
+	// TagDate returns the tagger date of annotated tags and the commit date of lightweight ones.
+	TagDate(tag string) (time.Time, error)
+	// Log returns the commits of the given revision range, newest first.
+	Log(revs ...string) ([]Commit, error)
+	// PatchIDs returns the patch ID of the given commits, keyed by full SHA.
+	// Commits without changes, like empty commits, are left out.
+	PatchIDs(shas ...string) (map[string]string, error)
+}
+
+// OpenRepository returns the repository of the context, read with the
+// backend selected by the git.backend config.
+func OpenRepository(ctx *context.Context) (Repository, error) {
+	switch ctx.Config.Git.Backend {
+	case "", backendCLI:
+		if ctx.GitRunner == nil {
+			if _, err := exec.LookPath("git"); err != nil {
+				return nil, errors.New("git not present in PATH, set git.backend to go-git to run without it")
+			}
+		}
+		return cliRepository{ctx: ctx}, nil
+	case backendGoGit:
+		return openGoGitRepository(ctx)
+	default:
+		return nil, fmt.Errorf("invalid git.backend: %q", ctx.Config.Git.Backend)
+	}
+}
+
+// cliRepository runs the git binary.
+type cliRepository struct {
+	ctx *context.Context
+}
+
+func (r cliRepository) IsRepo() bool {
+	out, err := Exec(r.ctx, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+func (r cliRepository) IsShallow() (bool, error) {
+	out, err := clean(Exec(r.ctx, "rev-parse", "--is-shallow-repository"))
+	return out == "true", err
+}
+
+func (r cliRepository) Unshallow() error {
+	_, err := Exec(r.ctx, "fetch", "--unshallow", "--tags")
+	return err
+}
+
+func (r cliRepository) RemoteURL() (string, error) {
+	return clean(Exec(r.ctx, "ls-remote", "--get-url"))
+}
+
+func (r cliRepository) Resolve(rev string) (string, error) {
+	return clean(Exec(r.ctx, "rev-list", "-n1", rev))
+}
+
+func (r cliRepository) FirstCommit() (string, error) {
+	return clean(Exec(r.ctx, "rev-list", "--max-parents=0", "HEAD"))
+}
+
+func (r cliRepository) TagsPointingAt(rev string) ([]string, error) {
+	var args []string
+	args = append(
+		args,
+		"tag",
+		"--points-at",
+		rev,
+		"--sort",
+		"-version:refname",
+	)
+	return cleanAllLines(Exec(r.ctx, args...))
+}
+
+func (r cliRepository) Describe(rev string, excluding []string) (string, error) {
+	args := []string{
+		"describe",
+		"--tags",
+		"--abbrev=0",
+		rev,
+	}
+	for _, exclude := range excluding {
+		args = append(args, "--exclude="+exclude)
+	}
+	return clean(Exec(r.ctx, args...))
+}
+
+func (r cliRepository) TagDate(tag string) (time.Time, error) {
+	out, err := clean(Exec(r.ctx, "for-each-ref", "--format=%(creatordate:iso-strict)", "refs/tags/"+tag))
+	if err != nil {
+		return time.Time{}, err
+	}
+	// the tag may not be found by its cleaned up name, leave its date empty.
+	if out == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, out)
+}
+
+func (r cliRepository) Log(revs ...string) ([]Commit, error) {
+	args := []string{
+		"log",
+		"--format=" + strings.Join([]string{"%h", "%cI", "%an", "%ae", "%B"}, fieldSep) + recordSep,
+		"--abbrev-commit",
+		"--no-decorate",
+		"--no-color",
+	}
+	out, err := Exec(r.ctx, append(args, revs...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(out, recordSep) {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, fieldSep, 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected git log output: %q", record)
+		}
+		commit := newCommit(fields[0], fields[4])
+		commit.Date, _ = time.Parse(time.RFC3339, fields[1])
+		commit.AuthorName = fields[2]
+		commit.AuthorEmail = fields[3]
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+func (r cliRepository) PatchIDs(shas ...string) (map[string]string, error) {
+	if len(shas) == 0 {
+		return nil, nil
+	}
+	args := []string{"show", "--no-color", "--format=commit %H", "-p"}
+	patches, err := Exec(r.ctx, append(args, shas...)...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := ExecWithInput(r.ctx, patches, "patch-id", "--stable")
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if id, sha, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			ids[sha] = id
+		}
+	}
+	return ids, nil
+}
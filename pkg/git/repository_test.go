@@ -0,0 +1,37 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/config"
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+func TestTagDate(t *testing.T) {
+	for name, tt := range map[string]struct {
+		out  string
+		want time.Time
+	}{
+		"date":    {"2024-05-01T10:00:00+02:00", time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+		"missing": {"", time.Time{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.New(config.Config{})
+			ctx.GitRunner = fakeRunner{
+				"for-each-ref --format=%(creatordate:iso-strict) refs/tags/v1.0.0": tt.out,
+			}
+			repo, err := OpenRepository(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := repo.TagDate("v1.0.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
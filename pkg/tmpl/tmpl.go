@@ -0,0 +1,93 @@
+// Package tmpl provides templating utilities for config string fields.
+package tmpl
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+// Template holds data that can be applied to a template string.
+type Template struct {
+	fields Fields
+}
+
+// Fields that will be available to the template engine.
+type Fields map[string]interface{}
+
+const (
+	env         = "Env"
+	version     = "Version"
+	tag         = "Tag"
+	previousTag = "PreviousTag"
+	commit      = "Commit"
+	firstCommit = "FirstCommit"
+	date        = "Date"
+)
+
+// dateFormat is the layout of the Date field.
+const dateFormat = "2006-01-02"
+
+// New Template.
+func New(ctx *context.Context) *Template {
+	return &Template{
+		fields: Fields{
+			env:         ctx.Env,
+			version:     ctx.Version,
+			tag:         ctx.Git.CurrentTag,
+			previousTag: ctx.Git.PreviousTag,
+			commit:      ctx.Git.Commit,
+			firstCommit: ctx.Git.FirstCommit,
+			date:        formatDate(ctx.Git.TagDate),
+		},
+	}
+}
+
+// formatDate formats the tag date, empty when it is unknown.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(dateFormat)
+}
+
+// WithExtraFields allows to add new more custom fields to the template.
+// It will override fields with the same name.
+func (t *Template) WithExtraFields(f Fields) *Template {
+	for k, v := range f {
+		t.fields[k] = v
+	}
+	return t
+}
+
+// Apply applies the given string against the Fields stored in the template.
+func (t *Template) Apply(s string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New("tmpl").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{
+			"replace":    strings.ReplaceAll,
+			"tolower":    strings.ToLower,
+			"toupper":    strings.ToUpper,
+			"trim":       strings.TrimSpace,
+			"trimprefix": strings.TrimPrefix,
+			"trimsuffix": strings.TrimSuffix,
+			"time": func(s string) string {
+				return time.Now().UTC().Format(s)
+			},
+		}).
+		Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	err = tmpl.Execute(&out, t.fields)
+	return out.String(), err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+// rewriteRule replaces the matches of re with replacement.
+type rewriteRule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// rewriter applies the replace rules to the text of the entries.
+type rewriter struct {
+	rules      []rewriteRule
+	capitalize bool
+}
+
+func newRewriter(ctx *context.Context) (rewriter, error) {
+	var rw rewriter
+	for _, rule := range ctx.Config.Changelog.Replace {
+		re, err := regexp.Compile(rule.Regexp)
+		if err != nil {
+			return rw, fmt.Errorf("invalid replace regexp %q: %w", rule.Regexp, err)
+		}
+		rw.rules = append(rw.rules, rewriteRule{re: re, replacement: rule.Replacement})
+	}
+	rw.capitalize = ctx.Config.Changelog.Capitalize
+	return rw, nil
+}
+
+// rewrite applies the rules to the message of an entry, without its commit SHA.
+func (rw rewriter) rewrite(msg string) string {
+	for _, rule := range rw.rules {
+		msg = rule.re.ReplaceAllString(msg, rule.replacement)
+	}
+	msg = strings.TrimSpace(msg)
+	if rw.capitalize && msg != "" {
+		r, size := utf8.DecodeRuneInString(msg)
+		msg = string(unicode.ToUpper(r)) + msg[size:]
+	}
+	return msg
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+)
+
+func TestRewriter(t *testing.T) {
+	for name, tt := range map[string]struct {
+		config string
+		msg    string
+		want   string
+	}{
+		"rules in order": {
+			config: `
+    - regexp: '^feat:'
+      replacement: 'feature:'
+    - regexp: '^feature: '
+      replacement: ''`,
+			msg:  "feat: login",
+			want: "login",
+		},
+		"capture groups": {
+			config: `
+    - regexp: '^(\w+)\((?P<scope>[^)]+)\): '
+      replacement: '${scope}: $1 '`,
+			msg:  "fix(api): timeout",
+			want: "api: fix timeout",
+		},
+		"every match": {
+			config: `
+    - regexp: 'Project X'
+      replacement: 'the app'`,
+			msg:  "rename Project X, Project X is public",
+			want: "rename the app, the app is public",
+		},
+		"trimmed": {
+			config: `
+    - regexp: '\[skip ci\]'
+      replacement: ''`,
+			msg:  "docs: typo [skip ci]",
+			want: "docs: typo",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rw, err := newRewriter(context.New(yamlConfig(t, "changelog:\n  replace:"+tt.config+"\n")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rw.rewrite(tt.msg); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriterCapitalize(t *testing.T) {
+	rw, err := newRewriter(context.New(yamlConfig(t, `
+changelog:
+  capitalize: true
+  replace:
+    - regexp: '^\w+: '
+      replacement: ''
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for msg, want := range map[string]string{
+		"fix: crash":      "Crash",
+		"fix: élan":       "Élan",
+		"already Capital": "Already Capital",
+		"fix: ":           "",
+	} {
+		if got := rw.rewrite(msg); got != want {
+			t.Errorf("rewrite(%q) = %q, want %q", msg, got, want)
+		}
+	}
+}
+
+func TestRewriterInvalid(t *testing.T) {
+	cfg := yamlConfig(t, "changelog:\n  replace:\n    - regexp: '(feat'\n")
+	_, err := newRewriter(context.New(cfg))
+	if err == nil || !strings.Contains(err.Error(), `invalid replace regexp "(feat"`) {
+		t.Fatalf("err = %v", err)
+	}
+}
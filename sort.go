@@ -0,0 +1,135 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sort keys.
+const (
+	sortMessage = "message"
+	sortDate    = "date"
+	sortAuthor  = "author"
+	sortScope   = "scope"
+	sortPR      = "pr"
+	sortTopo    = "topo"
+)
+
+// prRe matches the pull request number of squash and merge commit subjects.
+var prRe = regexp.MustCompile(`(?:\(#(\d+)\)\s*$|^Merge pull request #(\d+))`)
+
+// sortMode is a sort key along with its direction.
+type sortMode struct {
+	key  string
+	desc bool
+}
+
+// parseSortMode parses "key", "key-asc" or "key-desc". The historical "asc"
+// and "desc" values sort by message.
+func parseSortMode(s string) (sortMode, error) {
+	switch s {
+	case "":
+		return sortMode{}, nil
+	case "asc", "desc":
+		return sortMode{key: sortMessage, desc: s == "desc"}, nil
+	}
+
+	key, direction, _ := strings.Cut(s, "-")
+	switch key {
+	case sortMessage, sortDate, sortAuthor, sortScope, sortPR, sortTopo:
+	default:
+		return sortMode{}, fmt.Errorf("%w: %q", errInvalidSortDirection, s)
+	}
+	switch direction {
+	case "", "asc", "desc":
+	default:
+		return sortMode{}, fmt.Errorf("%w: %q", errInvalidSortDirection, s)
+	}
+	return sortMode{key: key, desc: direction == "desc"}, nil
+}
+
+// sort returns a sorted copy of the entries. Entries missing the sort key
+// keep their relative order and come last.
+func (m sortMode) sort(entries []entry) []entry {
+	if m.key == "" {
+		return entries
+	}
+	result := make([]entry, len(entries))
+	copy(result, entries)
+	sort.SliceStable(result, func(i, j int) bool {
+		a, aok := m.value(result[i])
+		b, bok := m.value(result[j])
+		if !aok || !bok {
+			return aok && !bok
+		}
+		if m.desc {
+			return a.compare(b) > 0
+		}
+		return a.compare(b) < 0
+	})
+	return result
+}
+
+// sortValue is either a string or a number.
+type sortValue struct {
+	s string
+	n int64
+}
+
+func (v sortValue) compare(o sortValue) int {
+	if c := cmp.Compare(v.n, o.n); c != 0 {
+		return c
+	}
+	return strings.Compare(v.s, o.s)
+}
+
+// value returns the sort key of the entry, or false if it has none.
+func (m sortMode) value(e entry) (sortValue, bool) {
+	switch m.key {
+	case sortDate:
+		return sortValue{n: e.commit.Date.Unix()}, !e.commit.Date.IsZero()
+	case sortAuthor:
+		author := e.commit.Login
+		if author == "" {
+			author = e.commit.AuthorName
+		}
+		return sortValue{s: strings.ToLower(author)}, author != ""
+	case sortScope:
+		scope := e.scope()
+		return sortValue{s: scope}, scope != ""
+	case sortPR:
+		n := e.pr()
+		return sortValue{n: int64(n)}, n != 0
+	case sortTopo:
+		return sortValue{n: int64(e.pos)}, true
+	default:
+		return sortValue{s: e.message()}, true
+	}
+}
+
+// message returns the commit subject of the entry, or the line of entries
+// without a commit like the bot summaries. Unlike the line, it doesn't
+// depend on abbrev.
+func (e entry) message() string {
+	if e.commit.SHA == "" {
+		return e.line
+	}
+	return e.commit.Subject
+}
+
+// pr returns the pull request number of the entry, or 0 if unknown.
+func (e entry) pr() int {
+	if e.commit.PullRequest != 0 {
+		return e.commit.PullRequest
+	}
+	m := prRe.FindStringSubmatch(e.commit.Subject)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1] + m[2])
+	return n
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varrcan/generate-pretty-changelog/pkg/context"
+	"github.com/varrcan/generate-pretty-changelog/pkg/git"
+)
+
+func TestParseSortMode(t *testing.T) {
+	for s, want := range map[string]sortMode{
+		"":          {},
+		"asc":       {key: sortMessage},
+		"desc":      {key: sortMessage, desc: true},
+		"date":      {key: sortDate},
+		"date-asc":  {key: sortDate},
+		"date-desc": {key: sortDate, desc: true},
+		"pr-desc":   {key: sortPR, desc: true},
+		"topo":      {key: sortTopo},
+	} {
+		got, err := parseSortMode(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: got %+v, want %+v", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"size", "date-up", "-desc", "ASC"} {
+		if _, err := parseSortMode(s); !errors.Is(err, errInvalidSortDirection) {
+			t.Errorf("%q: err = %v", s, err)
+		}
+	}
+}
+
+func TestSortModeSort(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+	entries := []entry{
+		{commit: git.Commit{SHA: "a", Subject: "fix(ui): b (#12)", Login: "zoe", Date: day(2)}, line: "a fix(ui): b (#12)", pos: 2},
+		{commit: git.Commit{SHA: "b", Subject: "feat: c", AuthorName: "Adam", Date: day(3)}, line: "b feat: c", pos: 0},
+		{commit: git.Commit{SHA: "c", Subject: "feat(api): a", PullRequest: 7}, line: "c feat(api): a", pos: 1},
+	}
+
+	for s, want := range map[string]string{
+		"":            "a b c",
+		"asc":         "c b a",
+		"desc":        "a b c",
+		"date":        "a b c",
+		"date-desc":   "b a c",
+		"author":      "b a c",
+		"author-desc": "a b c",
+		"scope":       "c a b",
+		"pr":          "c a b",
+		"pr-desc":     "a c b",
+		"topo":        "b c a",
+		"topo-desc":   "a c b",
+	} {
+		mode, err := parseSortMode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range mode.sort(entries) {
+			got = append(got, e.commit.SHA)
+		}
+		if strings.Join(got, " ") != want {
+			t.Errorf("%q: got %v, want %s", s, got, want)
+		}
+	}
+}
+
+func TestFormatChangelogGroupSortAbbrev(t *testing.T) {
+	entries := []entry{
+		{commit: git.Commit{SHA: "1111111", Subject: "zzz fix"}, line: "1111111 zzz fix"},
+		{commit: git.Commit{SHA: "2222222", Subject: "aaa wow"}, line: "2222222 aaa wow"},
+	}
+	for abbrev, want := range map[int]string{
+		-1: "## Changelog\n### Changes\n* aaa wow\n* zzz fix",
+		0:  "## Changelog\n### Changes\n* 2222222 aaa wow\n* 1111111 zzz fix",
+	} {
+		cfg := yamlConfig(t, "changelog:\n  groups:\n    - title: Changes\n      sort: message\n")
+		cfg.Changelog.Abbrev = abbrev
+		got, err := formatChangelog(context.New(cfg), entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("abbrev %d: got:\n%s\nwant:\n%s", abbrev, got, want)
+		}
+	}
+}